@@ -0,0 +1,421 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"gemini-cli-go/internal/config"
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/types"
+)
+
+// Account is a single OAuth credential set with its own token cache and project
+type Account struct {
+	label       string
+	credentials types.OAuth2Credentials
+	config      *config.Config
+
+	accessToken string
+	projectID   string
+	mu          sync.RWMutex
+	cache       map[string]*types.CachedTokenData
+	cacheMu     sync.RWMutex
+
+	// Scheduling and health state, guarded by statsMu. Gemini counts quota per model,
+	// so a rate limited account only cools down for the model that hit the limit.
+	statsMu         sync.Mutex
+	cooldowns       map[string]time.Time
+	lastUsed        time.Time
+	lastRateLimited time.Time
+	lastError       string
+	requests        int64
+	rateLimits      int64
+	failures        int64
+}
+
+// newAccount creates an account from a credential set, using defaultProjectID when it has no project_id
+func newAccount(label string, credentials types.OAuth2Credentials, defaultProjectID string, config *config.Config) *Account {
+	projectID := credentials.ProjectID
+	if projectID == "" {
+		projectID = defaultProjectID
+	}
+
+	return &Account{
+		label:       label,
+		credentials: credentials,
+		config:      config,
+		projectID:   projectID,
+		cache:       make(map[string]*types.CachedTokenData),
+		cooldowns:   make(map[string]time.Time),
+	}
+}
+
+// Label returns the name used for the account in logs and status output
+func (a *Account) Label() string {
+	return a.label
+}
+
+// InitializeAuth initializes authentication using OAuth2 credentials with caching
+func (a *Account) InitializeAuth() error {
+	// First, try to get a cached token
+	if cachedToken := a.getCachedToken(); cachedToken != nil {
+		timeUntilExpiry := time.Until(cachedToken.ExpiryDate)
+		if timeUntilExpiry > constants.TokenBufferTime {
+			a.mu.Lock()
+			a.accessToken = cachedToken.AccessToken
+			a.mu.Unlock()
+			return nil
+		}
+	}
+
+	// Check if the original token is still valid
+	expiryTime := time.Unix(a.credentials.ExpiryDate/1000, 0)
+	timeUntilExpiry := time.Until(expiryTime)
+
+	if timeUntilExpiry > constants.TokenBufferTime {
+		// Original token is still valid, cache it and use it
+		a.mu.Lock()
+		a.accessToken = a.credentials.AccessToken
+		a.mu.Unlock()
+
+		// Cache the token
+		a.cacheToken(a.credentials.AccessToken, expiryTime)
+		return nil
+	}
+
+	// Both original and cached tokens are expired, refresh the token
+	return a.refreshAndCacheToken(a.credentials.RefreshToken)
+}
+
+// refreshAndCacheToken refreshes the OAuth token and caches it
+func (a *Account) refreshAndCacheToken(refreshToken string) error {
+	data := url.Values{
+		"client_id":     {a.config.GetGoogleClientID()},
+		"client_secret": {a.config.GetGoogleClientSecret()},
+		"refresh_token": {refreshToken},
+		"grant_type":    {"refresh_token"},
+	}
+
+	resp, err := http.PostForm(constants.OAuthRefreshURL, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", constants.ErrTokenRefreshFailed, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP %d", constants.ErrTokenRefreshFailed, resp.StatusCode)
+	}
+
+	var refreshData types.TokenRefreshResponse
+	if err := json.NewDecoder(resp.Body).Decode(&refreshData); err != nil {
+		return fmt.Errorf("%s: %w", constants.ErrTokenRefreshFailed, err)
+	}
+
+	a.mu.Lock()
+	a.accessToken = refreshData.AccessToken
+	a.mu.Unlock()
+
+	// Calculate expiry time (typically 1 hour from now)
+	expiryTime := time.Now().Add(time.Duration(refreshData.ExpiresIn) * time.Second)
+
+	// Cache the new token
+	a.cacheToken(refreshData.AccessToken, expiryTime)
+
+	return nil
+}
+
+// cacheToken caches the access token with expiry time
+func (a *Account) cacheToken(accessToken string, expiryTime time.Time) {
+	a.cacheMu.Lock()
+	defer a.cacheMu.Unlock()
+
+	a.cache["oauth_token"] = &types.CachedTokenData{
+		AccessToken: accessToken,
+		ExpiryDate:  expiryTime,
+		CachedAt:    time.Now(),
+	}
+}
+
+// getCachedToken retrieves the cached token if it exists and is not expired
+func (a *Account) getCachedToken() *types.CachedTokenData {
+	a.cacheMu.Lock()
+	defer a.cacheMu.Unlock()
+
+	if token, exists := a.cache["oauth_token"]; exists {
+		if time.Now().Before(token.ExpiryDate) {
+			return token
+		}
+		// Token is expired, remove it from cache
+		delete(a.cache, "oauth_token")
+	}
+	return nil
+}
+
+// ClearTokenCache clears the cached token
+func (a *Account) ClearTokenCache() {
+	a.cacheMu.Lock()
+	delete(a.cache, "oauth_token")
+	a.cacheMu.Unlock()
+
+	a.mu.Lock()
+	a.accessToken = ""
+	a.mu.Unlock()
+}
+
+// GetCachedTokenInfo returns information about the cached token
+func (a *Account) GetCachedTokenInfo() *types.TokenCacheInfo {
+	a.cacheMu.RLock()
+	defer a.cacheMu.RUnlock()
+
+	if token, exists := a.cache["oauth_token"]; exists {
+		timeUntilExpiry := time.Until(token.ExpiryDate)
+		return &types.TokenCacheInfo{
+			Cached:                 true,
+			CachedAt:               token.CachedAt,
+			ExpiresAt:              token.ExpiryDate,
+			TimeUntilExpirySeconds: int(timeUntilExpiry.Seconds()),
+			IsExpired:              timeUntilExpiry <= 0,
+		}
+	}
+
+	return &types.TokenCacheInfo{
+		Cached:  false,
+		Message: "No token found in cache",
+	}
+}
+
+// CallEndpoint makes a generic API call to a Code Assist endpoint
+func (a *Account) CallEndpoint(method string, body interface{}) (interface{}, error) {
+	return a.callEndpointWithRetry(method, body, false)
+}
+
+// callEndpointWithRetry makes an API call with retry logic for 401 errors
+func (a *Account) callEndpointWithRetry(method string, body interface{}, isRetry bool) (interface{}, error) {
+	if err := a.InitializeAuth(); err != nil {
+		return nil, err
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s:%s", a.config.GetCodeAssistEndpoint(), constants.CodeAssistAPIVersion, method)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", constants.ContentTypeJSON)
+	req.Header.Set("Authorization", constants.BearerPrefix+a.GetAccessToken())
+
+	client := &http.Client{
+		Timeout: time.Duration(a.config.GetRequestTimeout()) * time.Second,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized && !isRetry {
+		// Clear token cache and retry once
+		a.ClearTokenCache()
+
+		if err := a.InitializeAuth(); err != nil {
+			return nil, err
+		}
+		return a.callEndpointWithRetry(method, body, true)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API call failed with status %d", resp.StatusCode)
+	}
+
+	var result interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return result, nil
+}
+
+// DiscoverProjectID returns the account's project ID, discovering it through the Code Assist API if needed
+func (a *Account) DiscoverProjectID() (string, error) {
+	a.mu.RLock()
+	projectID := a.projectID
+	a.mu.RUnlock()
+	if projectID != "" {
+		return projectID, nil
+	}
+
+	response, err := a.CallEndpoint("loadCodeAssist", map[string]interface{}{
+		"cloudaicompanionProject": "default-project",
+		"metadata": map[string]interface{}{
+			"duetProject": "default-project",
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", constants.ErrProjectDiscoveryFailed, err)
+	}
+
+	// Parse the response to extract project ID
+	if responseMap, ok := response.(map[string]interface{}); ok {
+		if projectID, exists := responseMap["cloudaicompanionProject"].(string); exists {
+			a.mu.Lock()
+			a.projectID = projectID
+			a.mu.Unlock()
+			return projectID, nil
+		}
+	}
+
+	return "", fmt.Errorf(constants.ErrProjectDiscoveryFailed)
+}
+
+// GetAccessToken returns the current access token
+func (a *Account) GetAccessToken() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.accessToken
+}
+
+// IsAuthenticated checks if the account holds an access token
+func (a *Account) IsAuthenticated() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.accessToken != ""
+}
+
+// TestAuthentication tests if the authentication is working
+func (a *Account) TestAuthentication() error {
+	if err := a.InitializeAuth(); err != nil {
+		a.recordError(err)
+		return err
+	}
+
+	// Try to make a simple API call to test authentication
+	_, err := a.CallEndpoint("loadCodeAssist", map[string]interface{}{
+		"cloudaicompanionProject": "test-project",
+		"metadata": map[string]interface{}{
+			"duetProject": "test-project",
+		},
+	})
+	if err != nil {
+		a.recordError(err)
+	}
+
+	return err
+}
+
+// MarkUsed records that a request was scheduled on the account
+func (a *Account) MarkUsed() {
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+	a.requests++
+	a.lastUsed = time.Now()
+}
+
+// MarkRateLimited puts the account into cooldown for a model after an upstream 429.
+// The upstream retry delay is used when given, the configured cooldown otherwise.
+func (a *Account) MarkRateLimited(model string, retryAfter time.Duration) {
+	cooldown := a.config.GetAccountCooldown()
+	if retryAfter > 0 {
+		cooldown = retryAfter
+	}
+
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+	a.rateLimits++
+	a.lastRateLimited = time.Now()
+	a.cooldowns[model] = a.lastRateLimited.Add(cooldown)
+}
+
+// MarkFailed records a failed request on the account
+func (a *Account) MarkFailed(err error) {
+	a.statsMu.Lock()
+	a.failures++
+	a.statsMu.Unlock()
+	a.recordError(err)
+}
+
+// recordError remembers the last error seen on the account
+func (a *Account) recordError(err error) {
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+	a.lastError = err.Error()
+}
+
+// lastRateLimitedAt returns when the account last hit a rate limit
+func (a *Account) lastRateLimitedAt() time.Time {
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+	return a.lastRateLimited
+}
+
+// coolingDown reports whether the account is cooling down for a model at the given time
+func (a *Account) coolingDown(model string, now time.Time) bool {
+	return now.Before(a.cooldownEnd(model))
+}
+
+// cooldownEnd returns when the cooldown of the account for a model ends
+func (a *Account) cooldownEnd(model string) time.Time {
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+	return a.cooldowns[model]
+}
+
+// Status returns the health of the account
+func (a *Account) Status() types.AccountStatus {
+	a.mu.RLock()
+	projectID := a.projectID
+	a.mu.RUnlock()
+
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+
+	status := types.AccountStatus{
+		Label:         a.label,
+		ProjectID:     projectID,
+		Authenticated: a.IsAuthenticated(),
+		State:         constants.AccountStateAvailable,
+		Requests:      a.requests,
+		RateLimits:    a.rateLimits,
+		Failures:      a.failures,
+		LastError:     a.lastError,
+		TokenCache:    a.GetCachedTokenInfo(),
+	}
+	if !a.lastUsed.IsZero() {
+		lastUsed := a.lastUsed
+		status.LastUsed = &lastUsed
+	}
+	if !a.lastRateLimited.IsZero() {
+		lastRateLimited := a.lastRateLimited
+		status.LastRateLimited = &lastRateLimited
+	}
+
+	// The account is cooling down while any model is, until the last cooldown ends
+	now := time.Now()
+	for model, cooldownUntil := range a.cooldowns {
+		if !now.Before(cooldownUntil) {
+			continue
+		}
+		if status.CooldownModels == nil {
+			status.CooldownModels = make(map[string]time.Time)
+		}
+		status.CooldownModels[model] = cooldownUntil
+		if status.CooldownUntil == nil || cooldownUntil.After(*status.CooldownUntil) {
+			until := cooldownUntil
+			status.State = constants.AccountStateCoolingDown
+			status.CooldownUntil = &until
+			status.CooldownRemainingSeconds = int(until.Sub(now).Seconds())
+		}
+	}
+
+	return status
+}
@@ -1,263 +1,243 @@
 package auth
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
-	"net/url"
+	"strings"
 	"sync"
 	"time"
 
+	"gemini-cli-go/internal/config"
 	"gemini-cli-go/internal/constants"
 	"gemini-cli-go/internal/types"
 )
 
-// AuthManager handles OAuth2 authentication and Google Code Assist API communication
+// AuthManager schedules requests across a pool of OAuth accounts
 type AuthManager struct {
-	config *types.Config
-	accessToken string
-	mu          sync.RWMutex
-	cache       map[string]*types.CachedTokenData
-	cacheMu     sync.RWMutex
+	config   *config.Config
+	accounts []*Account
+	loadErr  error
+	mu       sync.Mutex
+	next     int
 }
 
-// NewAuthManager creates a new AuthManager instance
-func NewAuthManager(config *types.Config) *AuthManager {
-	return &AuthManager{
-		config: config,
-		cache:  make(map[string]*types.CachedTokenData),
-	}
+// PoolExhaustedError is returned when every account is cooling down for the requested model
+type PoolExhaustedError struct {
+	RetryAfter time.Duration
 }
 
-// InitializeAuth initializes authentication using OAuth2 credentials with caching
-func (a *AuthManager) InitializeAuth() error {
-	if a.config.GetGCPServiceAccount() == "" {
-		return fmt.Errorf(constants.ErrMissingGCPServiceAccount)
+// Error implements the error interface
+func (e *PoolExhaustedError) Error() string {
+	return fmt.Sprintf("%s, retry in %ds", constants.ErrAllAccountsRateLimited, int(e.RetryAfter.Seconds()+0.5))
+}
+
+// NewAuthManager creates a new AuthManager with one account per credential set
+func NewAuthManager(config *config.Config) *AuthManager {
+	manager := &AuthManager{
+		config: config,
 	}
 
-	// First, try to get a cached token
-	if cachedToken := a.getCachedToken(); cachedToken != nil {
-		timeUntilExpiry := time.Until(cachedToken.ExpiryDate)
-		if timeUntilExpiry > constants.TokenBufferTime {
-			a.mu.Lock()
-			a.accessToken = cachedToken.AccessToken
-			a.mu.Unlock()
-			return nil
-		}
+	credentials, err := parseCredentialSets(config.GetGCPServiceAccount())
+	if err != nil {
+		manager.loadErr = err
+		return manager
 	}
 
-	// Parse original credentials from environment
-	var oauth2Creds types.OAuth2Credentials
-	if err := json.Unmarshal([]byte(a.config.GetGCPServiceAccount()), &oauth2Creds); err != nil {
-		return fmt.Errorf("%s: %w", constants.ErrInvalidOAuth2Credentials, err)
+	// GEMINI_PROJECT_ID only applies to the single-account setup, pooled accounts
+	// belong to different projects and set project_id themselves or have it discovered
+	defaultProjectID := ""
+	if len(credentials) == 1 {
+		defaultProjectID = config.GetGeminiProjectID()
 	}
 
-	// Check if the original token is still valid
-	expiryTime := time.Unix(oauth2Creds.ExpiryDate/1000, 0)
-	timeUntilExpiry := time.Until(expiryTime)
-	
-	if timeUntilExpiry > constants.TokenBufferTime {
-		// Original token is still valid, cache it and use it
-		a.mu.Lock()
-		a.accessToken = oauth2Creds.AccessToken
-		a.mu.Unlock()
-		
-		// Cache the token
-		a.cacheToken(oauth2Creds.AccessToken, expiryTime)
-		return nil
+	for i, creds := range credentials {
+		label := creds.Label
+		if label == "" {
+			label = fmt.Sprintf("account-%d", i+1)
+		}
+		manager.accounts = append(manager.accounts, newAccount(label, creds, defaultProjectID, config))
 	}
 
-	// Both original and cached tokens are expired, refresh the token
-	return a.refreshAndCacheToken(oauth2Creds.RefreshToken)
+	return manager
 }
 
-// refreshAndCacheToken refreshes the OAuth token and caches it
-func (a *AuthManager) refreshAndCacheToken(refreshToken string) error {
-	data := url.Values{
-		"client_id":     {a.config.GetGoogleClientID()},
-		"client_secret": {a.config.GetGoogleClientSecret()},
-		"refresh_token": {refreshToken},
-		"grant_type":    {"refresh_token"},
+// parseCredentialSets parses a single OAuth2 credential object or an array of them
+func parseCredentialSets(raw string) ([]types.OAuth2Credentials, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf(constants.ErrMissingGCPServiceAccount)
 	}
 
-	resp, err := http.PostForm(constants.OAuthRefreshURL, data)
-	if err != nil {
-		return fmt.Errorf("%s: %w", constants.ErrTokenRefreshFailed, err)
+	var credentials []types.OAuth2Credentials
+	if strings.HasPrefix(raw, "[") {
+		if err := json.Unmarshal([]byte(raw), &credentials); err != nil {
+			return nil, fmt.Errorf("%s: %w", constants.ErrInvalidOAuth2Credentials, err)
+		}
+		if len(credentials) == 0 {
+			return nil, fmt.Errorf(constants.ErrMissingGCPServiceAccount)
+		}
+		return credentials, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%s: HTTP %d", constants.ErrTokenRefreshFailed, resp.StatusCode)
+	var single types.OAuth2Credentials
+	if err := json.Unmarshal([]byte(raw), &single); err != nil {
+		return nil, fmt.Errorf("%s: %w", constants.ErrInvalidOAuth2Credentials, err)
 	}
+	return append(credentials, single), nil
+}
+
+// Accounts returns the accounts in the pool
+func (a *AuthManager) Accounts() []*Account {
+	return a.accounts
+}
 
-	var refreshData types.TokenRefreshResponse
-	if err := json.NewDecoder(resp.Body).Decode(&refreshData); err != nil {
-		return fmt.Errorf("%s: %w", constants.ErrTokenRefreshFailed, err)
+// Acquire picks the next account to use for a model according to the configured strategy,
+// skipping accounts that are cooling down for that model
+func (a *AuthManager) Acquire(model string) (*Account, error) {
+	if a.loadErr != nil {
+		return nil, a.loadErr
 	}
 
 	a.mu.Lock()
-	a.accessToken = refreshData.AccessToken
-	a.mu.Unlock()
-
-	// Calculate expiry time (typically 1 hour from now)
-	expiryTime := time.Now().Add(time.Duration(refreshData.ExpiresIn) * time.Second)
-	
-	// Cache the new token
-	a.cacheToken(refreshData.AccessToken, expiryTime)
-	
-	return nil
-}
+	defer a.mu.Unlock()
 
-// cacheToken caches the access token with expiry time
-func (a *AuthManager) cacheToken(accessToken string, expiryTime time.Time) {
-	a.cacheMu.Lock()
-	defer a.cacheMu.Unlock()
-	
-	a.cache["oauth_token"] = &types.CachedTokenData{
-		AccessToken: accessToken,
-		ExpiryDate:  expiryTime,
-		CachedAt:    time.Now(),
-	}
-}
+	now := time.Now()
+	var selected *Account
+	selectedIndex := -1
 
-// getCachedToken retrieves the cached token if it exists and is not expired
-func (a *AuthManager) getCachedToken() *types.CachedTokenData {
-	a.cacheMu.RLock()
-	defer a.cacheMu.RUnlock()
-	
-	if token, exists := a.cache["oauth_token"]; exists {
-		if time.Now().Before(token.ExpiryDate) {
-			return token
+	for offset := 0; offset < len(a.accounts); offset++ {
+		index := (a.next + offset) % len(a.accounts)
+		account := a.accounts[index]
+		if account.coolingDown(model, now) {
+			continue
 		}
-		// Token is expired, remove it from cache
-		delete(a.cache, "oauth_token")
-	}
-	return nil
-}
 
-// ClearTokenCache clears the cached token
-func (a *AuthManager) ClearTokenCache() {
-	a.cacheMu.Lock()
-	defer a.cacheMu.Unlock()
-	delete(a.cache, "oauth_token")
-}
+		if a.config.GetAccountStrategy() != constants.AccountStrategyLeastRecentlyLimited {
+			selected, selectedIndex = account, index
+			break
+		}
 
-// GetCachedTokenInfo returns information about the cached token
-func (a *AuthManager) GetCachedTokenInfo() *types.TokenCacheInfo {
-	a.cacheMu.RLock()
-	defer a.cacheMu.RUnlock()
-	
-	if token, exists := a.cache["oauth_token"]; exists {
-		timeUntilExpiry := time.Until(token.ExpiryDate)
-		return &types.TokenCacheInfo{
-			Cached:                 true,
-			CachedAt:               token.CachedAt,
-			ExpiresAt:              token.ExpiryDate,
-			TimeUntilExpirySeconds: int(timeUntilExpiry.Seconds()),
-			IsExpired:              timeUntilExpiry <= 0,
+		// Prefer the account that was rate limited longest ago, never limited ones first
+		if selected == nil || account.lastRateLimitedAt().Before(selected.lastRateLimitedAt()) {
+			selected, selectedIndex = account, index
 		}
 	}
-	
-	return &types.TokenCacheInfo{
-		Cached:  false,
-		Message: "No token found in cache",
+
+	if selected == nil {
+		return nil, &PoolExhaustedError{RetryAfter: a.earliestCooldownEnd(model, now).Sub(now)}
 	}
-}
 
-// CallEndpoint makes a generic API call to a Code Assist endpoint
-func (a *AuthManager) CallEndpoint(method string, body interface{}) (interface{}, error) {
-	return a.callEndpointWithRetry(method, body, false)
+	a.next = (selectedIndex + 1) % len(a.accounts)
+	selected.MarkUsed()
+	return selected, nil
 }
 
-// callEndpointWithRetry makes an API call with retry logic for 401 errors
-func (a *AuthManager) callEndpointWithRetry(method string, body interface{}, isRetry bool) (interface{}, error) {
-	if err := a.InitializeAuth(); err != nil {
-		return nil, err
+// earliestCooldownEnd returns when the first account becomes available again for a model
+func (a *AuthManager) earliestCooldownEnd(model string, now time.Time) time.Time {
+	var earliest time.Time
+	for _, account := range a.accounts {
+		cooldownUntil := account.cooldownEnd(model)
+		if earliest.IsZero() || cooldownUntil.Before(earliest) {
+			earliest = cooldownUntil
+		}
 	}
-
-	bodyBytes, err := json.Marshal(body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	if earliest.Before(now) {
+		return now
 	}
+	return earliest
+}
 
-	url := fmt.Sprintf("%s/%s:%s", constants.CodeAssistEndpoint, constants.CodeAssistAPIVersion, method)
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(bodyBytes))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// HasAvailableAccount reports whether any account is not cooling down for a model
+func (a *AuthManager) HasAvailableAccount(model string) bool {
+	now := time.Now()
+	for _, account := range a.accounts {
+		if !account.coolingDown(model, now) {
+			return true
+		}
 	}
+	return false
+}
 
-	a.mu.RLock()
-	token := a.accessToken
-	a.mu.RUnlock()
-
-	req.Header.Set("Content-Type", constants.ContentTypeJSON)
-	req.Header.Set("Authorization", constants.BearerPrefix+token)
-
-	client := &http.Client{
-		Timeout: time.Duration(a.config.GetRequestTimeout()) * time.Second,
+// primary returns the first account, used by the single-account debug endpoints
+func (a *AuthManager) primary() (*Account, error) {
+	if a.loadErr != nil {
+		return nil, a.loadErr
 	}
+	return a.accounts[0], nil
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+// InitializeAuth initializes authentication for every account in the pool
+func (a *AuthManager) InitializeAuth() error {
+	if a.loadErr != nil {
+		return a.loadErr
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusUnauthorized && !isRetry {
-		// Clear token cache and retry once
-		a.ClearTokenCache()
-		a.mu.Lock()
-		a.accessToken = ""
-		a.mu.Unlock()
-		
-		if err := a.InitializeAuth(); err != nil {
-			return nil, err
+
+	var errs []error
+	for _, account := range a.accounts {
+		if err := account.InitializeAuth(); err != nil {
+			account.recordError(err)
+			errs = append(errs, fmt.Errorf("%s: %w", account.Label(), err))
 		}
-		return a.callEndpointWithRetry(method, body, true)
 	}
+	return errors.Join(errs...)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API call failed with status %d", resp.StatusCode)
+// ClearTokenCache clears the cached tokens of every account
+func (a *AuthManager) ClearTokenCache() {
+	for _, account := range a.accounts {
+		account.ClearTokenCache()
 	}
+}
 
-	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+// GetCachedTokenInfo returns information about the cached token of the first account
+func (a *AuthManager) GetCachedTokenInfo() *types.TokenCacheInfo {
+	account, err := a.primary()
+	if err != nil {
+		return &types.TokenCacheInfo{
+			Cached: false,
+			Error:  err.Error(),
+		}
 	}
-
-	return result, nil
+	return account.GetCachedTokenInfo()
 }
 
-// GetAccessToken returns the current access token
+// GetAccessToken returns the current access token of the first account
 func (a *AuthManager) GetAccessToken() string {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-	return a.accessToken
+	account, err := a.primary()
+	if err != nil {
+		return ""
+	}
+	return account.GetAccessToken()
 }
 
-// IsAuthenticated checks if the user is authenticated
+// IsAuthenticated checks if any account is authenticated
 func (a *AuthManager) IsAuthenticated() bool {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-	return a.accessToken != ""
+	for _, account := range a.accounts {
+		if account.IsAuthenticated() {
+			return true
+		}
+	}
+	return false
 }
 
-// TestAuthentication tests if the authentication is working
+// TestAuthentication tests every account and succeeds if at least one works
 func (a *AuthManager) TestAuthentication() error {
-	if err := a.InitializeAuth(); err != nil {
-		return err
+	if a.loadErr != nil {
+		return a.loadErr
 	}
 
-	// Try to make a simple API call to test authentication
-	_, err := a.CallEndpoint("loadCodeAssist", map[string]interface{}{
-		"cloudaicompanionProject": "test-project",
-		"metadata": map[string]interface{}{
-			"duetProject": "test-project",
-		},
-	})
+	var errs []error
+	for _, account := range a.accounts {
+		if err := account.TestAuthentication(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", account.Label(), err))
+		}
+	}
 
-	return err
+	if len(errs) == len(a.accounts) {
+		return errors.Join(errs...)
+	}
+	return nil
 }
 
 // GetAuthenticationStatus returns detailed authentication status
@@ -265,6 +245,7 @@ func (a *AuthManager) GetAuthenticationStatus() map[string]interface{} {
 	status := map[string]interface{}{
 		"authenticated": a.IsAuthenticated(),
 		"token_cache":   a.GetCachedTokenInfo(),
+		"accounts":      a.AccountStatuses(),
 	}
 
 	if a.IsAuthenticated() {
@@ -274,4 +255,13 @@ func (a *AuthManager) GetAuthenticationStatus() map[string]interface{} {
 	}
 
 	return status
-}
\ No newline at end of file
+}
+
+// AccountStatuses returns the health of every account in the pool
+func (a *AuthManager) AccountStatuses() []types.AccountStatus {
+	statuses := make([]types.AccountStatus, 0, len(a.accounts))
+	for _, account := range a.accounts {
+		statuses = append(statuses, account.Status())
+	}
+	return statuses
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gemini-cli-go/internal/config"
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/types"
+)
+
+// poolCredentials is a pool of three accounts, only the first one has its own project
+const poolCredentials = `[
+	{"access_token":"a","refresh_token":"a","label":"first","project_id":"first-project"},
+	{"access_token":"b","refresh_token":"b","label":"second"},
+	{"access_token":"c","refresh_token":"c"}
+]`
+
+// newTestManager creates a manager for the credentials with the given strategy
+func newTestManager(t *testing.T, credentials, strategy string) *AuthManager {
+	t.Helper()
+	cfg := &config.Config{Environment: types.Environment{
+		GCPServiceAccount: credentials,
+		GeminiProjectID:   "env-project",
+		AccountStrategy:   strategy,
+		AccountCooldown:   60,
+	}}
+	return NewAuthManager(cfg)
+}
+
+// acquireLabels acquires n accounts for a model and returns their labels
+func acquireLabels(t *testing.T, manager *AuthManager, model string, n int) []string {
+	t.Helper()
+	var labels []string
+	for i := 0; i < n; i++ {
+		account, err := manager.Acquire(model)
+		require.NoError(t, err)
+		labels = append(labels, account.Label())
+	}
+	return labels
+}
+
+func TestNewAuthManager(t *testing.T) {
+	manager := newTestManager(t, poolCredentials, constants.AccountStrategyRoundRobin)
+	require.Len(t, manager.Accounts(), 3)
+
+	statuses := manager.AccountStatuses()
+	assert.Equal(t, "first", statuses[0].Label)
+	assert.Equal(t, "first-project", statuses[0].ProjectID)
+	assert.Equal(t, "second", statuses[1].Label)
+	assert.Empty(t, statuses[1].ProjectID, "GEMINI_PROJECT_ID must not be shared across pooled accounts")
+	assert.Equal(t, "account-3", statuses[2].Label)
+	assert.Empty(t, statuses[2].ProjectID)
+}
+
+func TestNewAuthManagerSingleAccountUsesProjectID(t *testing.T) {
+	manager := newTestManager(t, `{"access_token":"a","refresh_token":"a"}`, constants.AccountStrategyRoundRobin)
+	require.Len(t, manager.Accounts(), 1)
+	assert.Equal(t, "env-project", manager.AccountStatuses()[0].ProjectID)
+
+	manager = newTestManager(t, `[{"access_token":"a","refresh_token":"a"}]`, constants.AccountStrategyRoundRobin)
+	assert.Equal(t, "env-project", manager.AccountStatuses()[0].ProjectID)
+}
+
+func TestNewAuthManagerInvalidCredentials(t *testing.T) {
+	for _, credentials := range []string{"", "[]", "not json", "[{]"} {
+		manager := newTestManager(t, credentials, constants.AccountStrategyRoundRobin)
+		_, err := manager.Acquire("gemini-2.5-pro")
+		assert.Error(t, err, credentials)
+	}
+}
+
+func TestAcquireRoundRobin(t *testing.T) {
+	manager := newTestManager(t, poolCredentials, constants.AccountStrategyRoundRobin)
+	assert.Equal(t, []string{"first", "second", "account-3", "first"}, acquireLabels(t, manager, "gemini-2.5-pro", 4))
+}
+
+func TestAcquireSkipsAccountsCoolingDownForModel(t *testing.T) {
+	manager := newTestManager(t, poolCredentials, constants.AccountStrategyRoundRobin)
+	manager.Accounts()[1].MarkRateLimited("gemini-2.5-pro", 0)
+
+	assert.Equal(t, []string{"first", "account-3", "first"}, acquireLabels(t, manager, "gemini-2.5-pro", 3))
+	assert.True(t, manager.HasAvailableAccount("gemini-2.5-pro"))
+
+	// The cooldown only applies to the model that hit the limit
+	assert.Equal(t, []string{"second", "account-3", "first"}, acquireLabels(t, manager, "gemini-2.5-flash", 3))
+}
+
+func TestAcquirePoolExhausted(t *testing.T) {
+	manager := newTestManager(t, poolCredentials, constants.AccountStrategyRoundRobin)
+	accounts := manager.Accounts()
+	accounts[0].MarkRateLimited("gemini-2.5-pro", 30*time.Second)
+	accounts[1].MarkRateLimited("gemini-2.5-pro", 10*time.Second)
+	accounts[2].MarkRateLimited("gemini-2.5-pro", 0)
+
+	assert.False(t, manager.HasAvailableAccount("gemini-2.5-pro"))
+	_, err := manager.Acquire("gemini-2.5-pro")
+	var exhaustedErr *PoolExhaustedError
+	require.ErrorAs(t, err, &exhaustedErr)
+	assert.InDelta(t, 10*time.Second, exhaustedErr.RetryAfter, float64(time.Second))
+
+	assert.True(t, manager.HasAvailableAccount("gemini-2.5-flash"))
+	_, err = manager.Acquire("gemini-2.5-flash")
+	assert.NoError(t, err)
+}
+
+func TestAcquireLeastRecentlyLimited(t *testing.T) {
+	manager := newTestManager(t, poolCredentials, constants.AccountStrategyLeastRecentlyLimited)
+	accounts := manager.Accounts()
+	accounts[0].MarkRateLimited("gemini-2.5-pro", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	accounts[1].MarkRateLimited("gemini-2.5-pro", time.Nanosecond)
+
+	// Never limited accounts come first, then the one limited longest ago
+	assert.Equal(t, []string{"account-3", "account-3"}, acquireLabels(t, manager, "gemini-2.5-pro", 2))
+	accounts[2].MarkRateLimited("gemini-2.5-pro", time.Nanosecond)
+	assert.Equal(t, []string{"first"}, acquireLabels(t, manager, "gemini-2.5-pro", 1))
+}
+
+func TestAccountStatusCooldown(t *testing.T) {
+	manager := newTestManager(t, poolCredentials, constants.AccountStrategyRoundRobin)
+	account := manager.Accounts()[0]
+
+	status := account.Status()
+	assert.Equal(t, constants.AccountStateAvailable, status.State)
+	assert.Nil(t, status.CooldownUntil)
+
+	account.MarkRateLimited("gemini-2.5-pro", 0)
+	account.MarkRateLimited("gemini-2.5-flash", 30*time.Second)
+
+	status = account.Status()
+	assert.Equal(t, constants.AccountStateCoolingDown, status.State)
+	assert.Equal(t, int64(2), status.RateLimits)
+	assert.Len(t, status.CooldownModels, 2)
+	require.NotNil(t, status.CooldownUntil)
+	assert.Equal(t, status.CooldownModels["gemini-2.5-pro"], *status.CooldownUntil)
+	assert.InDelta(t, 60, status.CooldownRemainingSeconds, 1)
+}
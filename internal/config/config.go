@@ -1,10 +1,12 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"gemini-cli-go/internal/constants"
 	"gemini-cli-go/internal/types"
@@ -15,6 +17,9 @@ import (
 // Config holds the application configuration
 type Config struct {
 	Environment types.Environment
+
+	// SafetySettings are the default Gemini safety settings parsed from the environment
+	SafetySettings []types.GeminiSafetySetting
 }
 
 // New creates a new configuration instance
@@ -24,18 +29,41 @@ func New() (*Config, error) {
 
 	config := &Config{
 		Environment: types.Environment{
-			GCPServiceAccount:       getEnv(constants.EnvGCPServiceAccount, ""),
-			GeminiProjectID:         getEnv(constants.EnvGeminiProjectID, ""),
-			GoogleClientID:          getEnv(constants.EnvGoogleClientID, ""),
-			GoogleClientSecret:      getEnv(constants.EnvGoogleClientSecret, ""),
-			OpenAIAPIKey:            getEnv(constants.EnvOpenAIAPIKey, ""),
-			EnableFakeThinking:      getEnv(constants.EnvEnableFakeThinking, "false"),
-			EnableRealThinking:      getEnv(constants.EnvEnableRealThinking, "false"),
-			StreamThinkingAsContent: getEnv(constants.EnvStreamThinkingAsContent, "false"),
-			Port:                    getEnv(constants.EnvPort, constants.DefaultPort),
-			LogLevel:                getEnv(constants.EnvLogLevel, constants.DefaultLogLevel),
-			TokenCacheExpiry:        getEnvAsInt(constants.EnvTokenCacheExpiry, constants.DefaultTokenCacheExpiry),
-			RequestTimeout:          getEnvAsInt(constants.EnvRequestTimeout, constants.DefaultRequestTimeout),
+			GCPServiceAccount:        getEnv(constants.EnvGCPServiceAccount, ""),
+			GeminiProjectID:          getEnv(constants.EnvGeminiProjectID, ""),
+			CodeAssistEndpoint:       getEnv(constants.EnvCodeAssistEndpoint, constants.CodeAssistEndpoint),
+			GoogleClientID:           getEnv(constants.EnvGoogleClientID, ""),
+			GoogleClientSecret:       getEnv(constants.EnvGoogleClientSecret, ""),
+			OpenAIAPIKey:             getEnv(constants.EnvOpenAIAPIKey, ""),
+			EnableFakeThinking:       getEnv(constants.EnvEnableFakeThinking, "false"),
+			EnableRealThinking:       getEnv(constants.EnvEnableRealThinking, "false"),
+			StreamThinkingAsContent:  getEnv(constants.EnvStreamThinkingAsContent, "false"),
+			Port:                     getEnv(constants.EnvPort, constants.DefaultPort),
+			LogLevel:                 getEnv(constants.EnvLogLevel, constants.DefaultLogLevel),
+			TokenCacheExpiry:         getEnvAsInt(constants.EnvTokenCacheExpiry, constants.DefaultTokenCacheExpiry),
+			RequestTimeout:           getEnvAsInt(constants.EnvRequestTimeout, constants.DefaultRequestTimeout),
+			UpstreamConnectTimeout:   getEnvAsInt(constants.EnvUpstreamConnectTimeout, constants.DefaultUpstreamConnectTimeout),
+			UpstreamFirstByteTimeout: getEnvAsInt(constants.EnvUpstreamFirstByteTimeout, constants.DefaultUpstreamFirstByteTimeout),
+			StreamIdleTimeout:        getEnvAsInt(constants.EnvStreamIdleTimeout, constants.DefaultStreamIdleTimeout),
+			StreamMaxDuration:        getEnvAsInt(constants.EnvStreamMaxDuration, constants.DefaultStreamMaxDuration),
+			SchemaValidationRetries:  getEnvAsInt(constants.EnvSchemaValidationRetries, 0),
+			SystemPromptMode:         getEnv(constants.EnvSystemPromptMode, constants.SystemPromptModeNative),
+			SafetySettings:           getEnv(constants.EnvSafetySettings, ""),
+			RetryMaxRetries:          getEnvAsInt(constants.EnvRetryMaxRetries, constants.MaxRetries),
+			RetryInitialDelayMs:      getEnvAsInt(constants.EnvRetryInitialDelayMs, int(constants.RetryDelay/time.Millisecond)),
+			RetryMaxDelayMs:          getEnvAsInt(constants.EnvRetryMaxDelayMs, int(constants.RetryMaxDelay/time.Millisecond)),
+			RetryMaxElapsedMs:        getEnvAsInt(constants.EnvRetryMaxElapsedMs, int(constants.RetryMaxElapsed/time.Millisecond)),
+			AccountStrategy:          getEnv(constants.EnvAccountStrategy, constants.AccountStrategyRoundRobin),
+			AccountCooldown:          getEnvAsInt(constants.EnvAccountCooldown, constants.DefaultAccountCooldown),
+			ResponseStoreSize:        getEnvAsInt(constants.EnvResponseStoreSize, constants.DefaultResponseStoreSize),
+			ResponseStoreTTL:         getEnvAsInt(constants.EnvResponseStoreTTL, constants.DefaultResponseStoreTTL),
+			UnsupportedParams:        getEnv(constants.EnvUnsupportedParams, constants.UnsupportedParamsIgnore),
+			ReasoningFormat:          getEnv(constants.EnvReasoningFormat, constants.ReasoningFormatReasoning),
+			HistoryThinking:          getEnv(constants.EnvHistoryThinking, constants.HistoryThinkingStrip),
+			SSEHeartbeatInterval:     getEnvAsInt(constants.EnvSSEHeartbeatInterval, constants.DefaultSSEHeartbeatInterval),
+			CodeExecutionFormat:      getEnv(constants.EnvCodeExecutionFormat, constants.CodeExecutionFormatMarkdown),
+			WebSocketAllowedOrigins:  getEnv(constants.EnvWebSocketAllowedOrigins, ""),
+			WebSocketMaxConcurrent:   getEnvAsInt(constants.EnvWebSocketMaxConcurrent, constants.DefaultWebSocketMaxConcurrent),
 		},
 	}
 
@@ -60,6 +88,63 @@ func (c *Config) Validate() error {
 		c.Environment.LogLevel = constants.DefaultLogLevel
 	}
 
+	// Validate system prompt mode
+	validSystemPromptModes := []string{
+		constants.SystemPromptModeNative,
+		constants.SystemPromptModePrepend,
+	}
+
+	if !contains(validSystemPromptModes, c.Environment.SystemPromptMode) {
+		c.Environment.SystemPromptMode = constants.SystemPromptModeNative
+	}
+
+	// Validate account scheduling strategy
+	validAccountStrategies := []string{
+		constants.AccountStrategyRoundRobin,
+		constants.AccountStrategyLeastRecentlyLimited,
+	}
+
+	if !contains(validAccountStrategies, c.Environment.AccountStrategy) {
+		c.Environment.AccountStrategy = constants.AccountStrategyRoundRobin
+	}
+
+	// Validate unsupported parameter handling
+	validUnsupportedParams := []string{
+		constants.UnsupportedParamsIgnore,
+		constants.UnsupportedParamsReject,
+	}
+
+	if !contains(validUnsupportedParams, c.Environment.UnsupportedParams) {
+		c.Environment.UnsupportedParams = constants.UnsupportedParamsIgnore
+	}
+
+	// Validate reasoning format
+	if !IsValidReasoningFormat(c.Environment.ReasoningFormat) {
+		c.Environment.ReasoningFormat = constants.ReasoningFormatReasoning
+	}
+
+	// Validate history thinking handling
+	validHistoryThinking := []string{
+		constants.HistoryThinkingStrip,
+		constants.HistoryThinkingKeep,
+	}
+
+	if !contains(validHistoryThinking, c.Environment.HistoryThinking) {
+		c.Environment.HistoryThinking = constants.HistoryThinkingStrip
+	}
+
+	// Validate code execution format
+	if !IsValidCodeExecutionFormat(c.Environment.CodeExecutionFormat) {
+		c.Environment.CodeExecutionFormat = constants.CodeExecutionFormatMarkdown
+	}
+
+	// Validate safety settings
+	safetySettings, err := parseSafetySettings(c.Environment.SafetySettings)
+	if err != nil {
+		return err
+	}
+	c.SafetySettings = safetySettings
+
 	// Validate port
 	if c.Environment.Port == "" {
 		c.Environment.Port = constants.DefaultPort
@@ -103,6 +188,146 @@ func (c *Config) GetRequestTimeout() int {
 	return c.Environment.RequestTimeout
 }
 
+// GetUpstreamConnectTimeout returns how long connecting to the upstream may take, 0 disables the limit
+func (c *Config) GetUpstreamConnectTimeout() time.Duration {
+	return time.Duration(c.Environment.UpstreamConnectTimeout) * time.Second
+}
+
+// GetUpstreamFirstByteTimeout returns how long an upstream stream may take to deliver its first byte, 0 disables the limit
+func (c *Config) GetUpstreamFirstByteTimeout() time.Duration {
+	return time.Duration(c.Environment.UpstreamFirstByteTimeout) * time.Second
+}
+
+// GetStreamIdleTimeout returns how long an upstream stream may stay silent between chunks, 0 disables the limit
+func (c *Config) GetStreamIdleTimeout() time.Duration {
+	return time.Duration(c.Environment.StreamIdleTimeout) * time.Second
+}
+
+// GetStreamMaxDuration returns the maximum total duration of a streaming request, 0 disables the limit
+func (c *Config) GetStreamMaxDuration() time.Duration {
+	return time.Duration(c.Environment.StreamMaxDuration) * time.Second
+}
+
+// GetSchemaValidationRetries returns how often a completion is retried when it does not match its response format
+func (c *Config) GetSchemaValidationRetries() int {
+	if c.Environment.SchemaValidationRetries < 0 {
+		return 0
+	}
+	return c.Environment.SchemaValidationRetries
+}
+
+// GetSystemPromptMode returns how system prompts are sent to Gemini
+func (c *Config) GetSystemPromptMode() string {
+	return c.Environment.SystemPromptMode
+}
+
+// GetRetryMaxRetries returns how often a failed upstream request is retried
+func (c *Config) GetRetryMaxRetries() int {
+	if c.Environment.RetryMaxRetries < 0 {
+		return 0
+	}
+	return c.Environment.RetryMaxRetries
+}
+
+// GetRetryInitialDelay returns the backoff before the first retry
+func (c *Config) GetRetryInitialDelay() time.Duration {
+	return time.Duration(c.Environment.RetryInitialDelayMs) * time.Millisecond
+}
+
+// GetRetryMaxDelay returns the maximum backoff between retries
+func (c *Config) GetRetryMaxDelay() time.Duration {
+	return time.Duration(c.Environment.RetryMaxDelayMs) * time.Millisecond
+}
+
+// GetRetryMaxElapsed returns the maximum time spent retrying a request
+func (c *Config) GetRetryMaxElapsed() time.Duration {
+	return time.Duration(c.Environment.RetryMaxElapsedMs) * time.Millisecond
+}
+
+// GetReasoningFormat returns where reasoning is placed in OpenAI responses by default
+func (c *Config) GetReasoningFormat() string {
+	return c.Environment.ReasoningFormat
+}
+
+// KeepHistoryThinking returns true if thinking in assistant history is sent as thought parts instead of removed
+func (c *Config) KeepHistoryThinking() bool {
+	return c.Environment.HistoryThinking == constants.HistoryThinkingKeep
+}
+
+// IsValidReasoningFormat checks if the given reasoning format is supported
+func IsValidReasoningFormat(format string) bool {
+	return contains([]string{
+		constants.ReasoningFormatReasoning,
+		constants.ReasoningFormatReasoningContent,
+		constants.ReasoningFormatThinkTags,
+	}, format)
+}
+
+// GetCodeExecutionFormat returns how code execution parts are returned in OpenAI responses by default
+func (c *Config) GetCodeExecutionFormat() string {
+	return c.Environment.CodeExecutionFormat
+}
+
+// IsValidCodeExecutionFormat checks if the given code execution format is supported
+func IsValidCodeExecutionFormat(format string) bool {
+	return contains([]string{
+		constants.CodeExecutionFormatMarkdown,
+		constants.CodeExecutionFormatStructured,
+	}, format)
+}
+
+// RejectUnsupportedParams returns true if requests using parameters Gemini cannot honour fail
+func (c *Config) RejectUnsupportedParams() bool {
+	return c.Environment.UnsupportedParams == constants.UnsupportedParamsReject
+}
+
+// GetAccountStrategy returns how requests are scheduled across accounts
+func (c *Config) GetAccountStrategy() string {
+	return c.Environment.AccountStrategy
+}
+
+// GetAccountCooldown returns how long a rate limited account is skipped
+func (c *Config) GetAccountCooldown() time.Duration {
+	return time.Duration(c.Environment.AccountCooldown) * time.Second
+}
+
+// GetResponseStoreSize returns how many responses are kept for previous_response_id
+func (c *Config) GetResponseStoreSize() int {
+	return c.Environment.ResponseStoreSize
+}
+
+// GetResponseStoreTTL returns how long stored responses are kept
+func (c *Config) GetResponseStoreTTL() time.Duration {
+	return time.Duration(c.Environment.ResponseStoreTTL) * time.Second
+}
+
+// GetSSEHeartbeatInterval returns how long a stream may stay silent before a heartbeat is sent, 0 disables heartbeats
+func (c *Config) GetSSEHeartbeatInterval() time.Duration {
+	return time.Duration(c.Environment.SSEHeartbeatInterval) * time.Second
+}
+
+// GetWebSocketAllowedOrigins returns the browser origins allowed to open a WebSocket besides
+// the server's own, "*" allows any origin
+func (c *Config) GetWebSocketAllowedOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(c.Environment.WebSocketAllowedOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// GetWebSocketMaxConcurrent returns how many completions may run at once on a WebSocket, 0 disables the limit
+func (c *Config) GetWebSocketMaxConcurrent() int {
+	return c.Environment.WebSocketMaxConcurrent
+}
+
+// GetSafetySettings returns the default Gemini safety settings
+func (c *Config) GetSafetySettings() []types.GeminiSafetySetting {
+	return c.SafetySettings
+}
+
 // GetLogLevel returns the configured log level
 func (c *Config) GetLogLevel() string {
 	return c.Environment.LogLevel
@@ -118,6 +343,14 @@ func (c *Config) GetGeminiProjectID() string {
 	return c.Environment.GeminiProjectID
 }
 
+// GetCodeAssistEndpoint returns the base URL of the Code Assist API
+func (c *Config) GetCodeAssistEndpoint() string {
+	if c.Environment.CodeAssistEndpoint == "" {
+		return constants.CodeAssistEndpoint
+	}
+	return strings.TrimSuffix(c.Environment.CodeAssistEndpoint, "/")
+}
+
 // GetOpenAIAPIKey returns the OpenAI API key
 func (c *Config) GetOpenAIAPIKey() string {
 	return c.Environment.OpenAIAPIKey
@@ -133,8 +366,53 @@ func (c *Config) GetGoogleClientSecret() string {
 	return c.Environment.GoogleClientSecret
 }
 
+// ValidateSafetySettings checks that every safety setting names a harm category and a known threshold
+func ValidateSafetySettings(settings []types.GeminiSafetySetting) error {
+	for _, setting := range settings {
+		if !strings.HasPrefix(setting.Category, constants.HarmCategoryPrefix) {
+			return fmt.Errorf("%s: unknown harm category %q", constants.ErrInvalidSafetySettings, setting.Category)
+		}
+		if !constants.SafetyThresholds[setting.Threshold] {
+			return fmt.Errorf("%s: unknown threshold %q for %s", constants.ErrInvalidSafetySettings, setting.Threshold, setting.Category)
+		}
+	}
+	return nil
+}
+
 // Helper functions
 
+// parseSafetySettings parses safety settings given either as a JSON array or
+// as a comma separated list of CATEGORY=THRESHOLD pairs
+func parseSafetySettings(value string) ([]types.GeminiSafetySetting, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	var settings []types.GeminiSafetySetting
+	if strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), &settings); err != nil {
+			return nil, fmt.Errorf("%s: %w", constants.ErrInvalidSafetySettings, err)
+		}
+	} else {
+		for _, pair := range strings.Split(value, ",") {
+			category, threshold, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if !found {
+				return nil, fmt.Errorf("%s: expected CATEGORY=THRESHOLD, got %q", constants.ErrInvalidSafetySettings, pair)
+			}
+			settings = append(settings, types.GeminiSafetySetting{
+				Category:  strings.TrimSpace(category),
+				Threshold: strings.TrimSpace(threshold),
+			})
+		}
+	}
+
+	if err := ValidateSafetySettings(settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
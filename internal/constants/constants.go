@@ -13,10 +13,11 @@ const (
 
 	// OpenAI API Constants
 	OpenAIChatCompletionObject = "chat.completion.chunk"
-	OpenAIModelOwner          = "google-gemini-cli"
-	OpenAICompletionObject    = "chat.completion"
-	OpenAIModelObject         = "model"
-	OpenAIModelListObject     = "list"
+	OpenAIModelOwner           = "google-gemini-cli"
+	OpenAICompletionObject     = "chat.completion"
+	OpenAIModelObject          = "model"
+	OpenAIModelListObject      = "list"
+	OpenAITextCompletionObject = "text_completion"
 
 	// Default values
 	DefaultModel       = "gemini-2.5-flash"
@@ -25,33 +26,57 @@ const (
 	DefaultTemperature = 0.7
 
 	// Token management
-	TokenBufferTime       = 5 * time.Minute
-	DefaultTokenCacheExpiry = 3600 // seconds
-	DefaultRequestTimeout   = 30   // seconds
+	TokenBufferTime                 = 5 * time.Minute
+	DefaultTokenCacheExpiry         = 3600 // seconds
+	DefaultRequestTimeout           = 30   // seconds
+	DefaultUpstreamConnectTimeout   = 10   // seconds
+	DefaultUpstreamFirstByteTimeout = 60   // seconds
+	DefaultStreamIdleTimeout        = 60   // seconds
+	DefaultStreamMaxDuration        = 600  // seconds
+	DefaultAccountCooldown          = 60   // seconds
+	DefaultResponseStoreSize        = 1000
+	DefaultResponseStoreTTL         = 3600 // seconds
+	DefaultSSEHeartbeatInterval     = 15   // seconds, 0 disables heartbeats
+	DefaultWebSocketMaxConcurrent   = 8    // completions per connection, 0 disables the limit
 
 	// Thinking budget constants
 	DefaultThinkingBudget  = -1 // -1 means dynamic allocation by Gemini
 	DisabledThinkingBudget = 0  // 0 disables thinking entirely
 
+	// OpenAI reasoning efforts, mapped to the thinking budgets of each model
+	ReasoningEffortNone    = "none"
+	ReasoningEffortMinimal = "minimal"
+	ReasoningEffortLow     = "low"
+	ReasoningEffortMedium  = "medium"
+	ReasoningEffortHigh    = "high"
+
 	// Streaming constants
-	ReasoningChunkDelay         = 100 * time.Millisecond
-	ThinkingContentChunkSize    = 15
-	StreamingChunkDelay         = 50 * time.Millisecond
-	SSEDataPrefix              = "data: "
-	SSEDoneMessage             = "data: [DONE]"
-	SSENewLine                 = "\n"
+	ReasoningChunkDelay      = 100 * time.Millisecond
+	ThinkingContentChunkSize = 15
+	StreamingChunkDelay      = 50 * time.Millisecond
+	SSEDataPrefix            = "data: "
+	SSEDoneMessage           = "data: [DONE]"
+	SSEHeartbeatMessage      = ": keep-alive"
+	SSENewLine               = "\n"
 
 	// HTTP headers
-	ContentTypeJSON        = "application/json"
-	ContentTypeSSE         = "text/event-stream"
+	ContentTypeJSON           = "application/json"
+	ContentTypeSSE            = "text/event-stream"
 	ContentTypeFormURLEncoded = "application/x-www-form-urlencoded"
-	AuthorizationHeader    = "Authorization"
-	BearerPrefix          = "Bearer "
+	AuthorizationHeader       = "Authorization"
+	BearerPrefix              = "Bearer "
+	APIKeyHeader              = "x-api-key"
+	GoogleAPIKeyHeader        = "x-goog-api-key"
+	APIKeyQueryParam          = "key"
+	WebSocketProtocolHeader   = "Sec-WebSocket-Protocol"
 
 	// CORS headers
 	CORSAllowOrigin  = "*"
 	CORSAllowMethods = "GET, POST, OPTIONS"
-	CORSAllowHeaders = "Content-Type, Authorization"
+	CORSAllowHeaders = "Content-Type, Authorization, x-api-key, anthropic-version, x-goog-api-key"
+
+	// HeaderGeminiFallback names the model that answered when a fallback model was used
+	HeaderGeminiFallback = "x-gemini-fallback"
 
 	// Cache control
 	CacheControlNoCache = "no-cache"
@@ -62,29 +87,59 @@ const (
 	ContextWindowDefault = 1048576
 
 	// Environment variable names
-	EnvGCPServiceAccount      = "GCP_SERVICE_ACCOUNT"
-	EnvGeminiProjectID        = "GEMINI_PROJECT_ID"
-	EnvGoogleClientID         = "GOOGLE_CLIENT_ID"
-	EnvGoogleClientSecret     = "GOOGLE_CLIENT_SECRET"
-	EnvOpenAIAPIKey           = "OPENAI_API_KEY"
-	EnvEnableFakeThinking     = "ENABLE_FAKE_THINKING"
-	EnvEnableRealThinking     = "ENABLE_REAL_THINKING"
-	EnvStreamThinkingAsContent = "STREAM_THINKING_AS_CONTENT"
-	EnvPort                   = "PORT"
-	EnvLogLevel               = "LOG_LEVEL"
-	EnvTokenCacheExpiry       = "TOKEN_CACHE_EXPIRY"
-	EnvRequestTimeout         = "REQUEST_TIMEOUT"
+	EnvGCPServiceAccount        = "GCP_SERVICE_ACCOUNT"
+	EnvGeminiProjectID          = "GEMINI_PROJECT_ID"
+	EnvCodeAssistEndpoint       = "CODE_ASSIST_ENDPOINT"
+	EnvGoogleClientID           = "GOOGLE_CLIENT_ID"
+	EnvGoogleClientSecret       = "GOOGLE_CLIENT_SECRET"
+	EnvOpenAIAPIKey             = "OPENAI_API_KEY"
+	EnvEnableFakeThinking       = "ENABLE_FAKE_THINKING"
+	EnvEnableRealThinking       = "ENABLE_REAL_THINKING"
+	EnvStreamThinkingAsContent  = "STREAM_THINKING_AS_CONTENT"
+	EnvPort                     = "PORT"
+	EnvLogLevel                 = "LOG_LEVEL"
+	EnvTokenCacheExpiry         = "TOKEN_CACHE_EXPIRY"
+	EnvRequestTimeout           = "REQUEST_TIMEOUT"
+	EnvUpstreamConnectTimeout   = "UPSTREAM_CONNECT_TIMEOUT"
+	EnvUpstreamFirstByteTimeout = "UPSTREAM_FIRST_BYTE_TIMEOUT"
+	EnvStreamIdleTimeout        = "STREAM_IDLE_TIMEOUT"
+	EnvStreamMaxDuration        = "STREAM_MAX_DURATION"
+	EnvSchemaValidationRetries  = "SCHEMA_VALIDATION_RETRIES"
+	EnvSystemPromptMode         = "SYSTEM_PROMPT_MODE"
+	EnvSafetySettings           = "SAFETY_SETTINGS"
+	EnvRetryMaxRetries          = "RETRY_MAX_RETRIES"
+	EnvRetryInitialDelayMs      = "RETRY_INITIAL_DELAY_MS"
+	EnvRetryMaxDelayMs          = "RETRY_MAX_DELAY_MS"
+	EnvRetryMaxElapsedMs        = "RETRY_MAX_ELAPSED_MS"
+	EnvAccountStrategy          = "ACCOUNT_STRATEGY"
+	EnvAccountCooldown          = "ACCOUNT_COOLDOWN"
+	EnvResponseStoreSize        = "RESPONSE_STORE_SIZE"
+	EnvResponseStoreTTL         = "RESPONSE_STORE_TTL"
+	EnvUnsupportedParams        = "UNSUPPORTED_PARAMS"
+	EnvReasoningFormat          = "REASONING_FORMAT"
+	EnvHistoryThinking          = "HISTORY_THINKING"
+	EnvSSEHeartbeatInterval     = "SSE_HEARTBEAT_INTERVAL"
+	EnvCodeExecutionFormat      = "CODE_EXECUTION_FORMAT"
+	EnvWebSocketAllowedOrigins  = "WEBSOCKET_ALLOWED_ORIGINS"
+	EnvWebSocketMaxConcurrent   = "WEBSOCKET_MAX_CONCURRENT"
 
 	// API paths
-	PathV1           = "/v1"
-	PathModels       = "/models"
-	PathChatCompletions = "/chat/completions"
-	PathDebug        = "/debug"
-	PathDebugCache   = "/debug/cache"
-	PathTokenTest    = "/token-test"
-	PathTest         = "/test"
-	PathHealth       = "/health"
-	PathRoot         = "/"
+	PathV1                  = "/v1"
+	PathModels              = "/models"
+	PathChatCompletions     = "/chat/completions"
+	PathChatWebSocket       = "/chat/ws"
+	PathMessages            = "/messages"
+	PathMessagesCountTokens = "/messages/count_tokens"
+	PathResponses           = "/responses"
+	PathCompletions         = "/completions"
+	PathV1Beta              = "/v1beta"
+	PathNativeModelAction   = "/models/:modelAction"
+	PathDebug               = "/debug"
+	PathDebugCache          = "/debug/cache"
+	PathTokenTest           = "/token-test"
+	PathTest                = "/test"
+	PathHealth              = "/health"
+	PathRoot                = "/"
 
 	// Error messages
 	ErrMissingGCPServiceAccount = "GCP_SERVICE_ACCOUNT environment variable not set"
@@ -98,6 +153,10 @@ const (
 	ErrMissingMessages          = "messages is a required field"
 	ErrStreamRequestFailed      = "Stream request failed"
 	ErrInvalidRequest           = "Invalid request"
+	ErrResponseFormatMismatch   = "Model output does not match the requested response_format"
+	ErrPromptBlocked            = "Prompt was blocked by Gemini"
+	ErrInvalidSafetySettings    = "Invalid safety settings"
+	ErrAllAccountsRateLimited   = "All accounts are rate limited"
 
 	// Success messages
 	MsgHealthOK            = "OK"
@@ -105,16 +164,88 @@ const (
 	MsgTokenRefreshed      = "Token refreshed successfully"
 	MsgProjectDiscovered   = "Project ID discovered successfully"
 
+	// System prompt modes
+	SystemPromptModeNative  = "native"  // send as Gemini systemInstruction
+	SystemPromptModePrepend = "prepend" // send as a leading user turn (legacy behaviour)
+
+	// Handling of request parameters Gemini cannot honour
+	UnsupportedParamsIgnore = "ignore" // drop them with a log line
+	UnsupportedParamsReject = "reject" // fail the request with 400
+
+	// Handling of thinking echoed back in assistant history
+	HistoryThinkingStrip = "strip" // remove it
+	HistoryThinkingKeep  = "keep"  // send it as Gemini thought parts
+
+	// Account scheduling strategies
+	AccountStrategyRoundRobin           = "round_robin"
+	AccountStrategyLeastRecentlyLimited = "least_recently_limited"
+
+	// Account states reported on the status endpoint
+	AccountStateAvailable   = "available"
+	AccountStateCoolingDown = "cooling_down"
+
+	// ConversationStartPlaceholder is the user turn inserted before histories that start with the model
+	ConversationStartPlaceholder = "Continue."
+
+	// CompletionSystemPrompt turns a chat model into a text completion engine for /v1/completions
+	CompletionSystemPrompt = "You are a text completion engine. Continue the text provided by the user exactly where it ends. Reply with the continuation only, without repeating the text or adding any commentary."
+
+	// CompletionInsertSystemPrompt is used instead when a suffix is given
+	CompletionInsertSystemPrompt = "You are a text completion engine. The user provides the text before and after a gap, in <prefix> and <suffix> tags. Reply with the text that fills the gap only, without repeating the prefix or suffix or adding any commentary."
+
 	// Thinking tags
 	ThinkingOpenTag  = "<thinking>\n"
 	ThinkingCloseTag = "\n</thinking>\n\n"
 
+	// Reasoning formats, where reasoning is placed in OpenAI responses
+	ReasoningFormatReasoning        = "reasoning"         // delta.reasoning / message.reasoning (OpenRouter)
+	ReasoningFormatReasoningContent = "reasoning_content" // delta.reasoning_content / message.reasoning_content (DeepSeek)
+	ReasoningFormatThinkTags        = "think_tags"        // content wrapped in <think> tags (Open WebUI)
+
+	// Code execution formats, how Gemini's code execution parts are returned in OpenAI responses
+	CodeExecutionFormatMarkdown   = "markdown"   // fenced code and output blocks in content
+	CodeExecutionFormatStructured = "structured" // delta.code_execution / message.code_execution
+
+	// Code execution item types of the structured format
+	CodeExecutionTypeExecutableCode = "executable_code"
+	CodeExecutionTypeResult         = "code_execution_result"
+
+	// CodeExecutionOutcomeOK is the outcome of code that ran successfully
+	CodeExecutionOutcomeOK = "OUTCOME_OK"
+
+	// Think tags used by the think_tags reasoning format
+	ThinkOpenTag  = "<think>\n"
+	ThinkCloseTag = "\n</think>\n\n"
+
+	// OpenAI finish reasons
+	FinishReasonStop          = "stop"
+	FinishReasonLength        = "length"
+	FinishReasonContentFilter = "content_filter"
+	FinishReasonToolCalls     = "tool_calls"
+
+	// OpenAI annotation types
+	AnnotationTypeURLCitation = "url_citation"
+
+	// Anthropic stop reasons
+	StopReasonEndTurn   = "end_turn"
+	StopReasonMaxTokens = "max_tokens"
+	StopReasonToolUse   = "tool_use"
+	StopReasonRefusal   = "refusal"
+
 	// Request/Response IDs
-	ChatCompletionIDPrefix = "chatcmpl-"
-	
+	ChatCompletionIDPrefix   = "chatcmpl-"
+	AnthropicMessageIDPrefix = "msg_"
+	ResponseIDPrefix         = "resp_"
+	CompletionIDPrefix       = "cmpl-"
+
+	// ResponsesIncludeLogprobs is the Responses API include that requests logprobs
+	ResponsesIncludeLogprobs = "message.output_text.logprobs"
+
 	// Retry settings
-	MaxRetries = 3
-	RetryDelay = 1 * time.Second
+	MaxRetries      = 3
+	RetryDelay      = 1 * time.Second
+	RetryMaxDelay   = 30 * time.Second
+	RetryMaxElapsed = 60 * time.Second
 )
 
 // Static reasoning messages for thinking models
@@ -135,6 +266,36 @@ var SupportedImageMimeTypes = map[string]bool{
 	"image/webp": true,
 }
 
+// Harm block thresholds accepted in Gemini safetySettings
+var SafetyThresholds = map[string]bool{
+	"HARM_BLOCK_THRESHOLD_UNSPECIFIED": true,
+	"BLOCK_LOW_AND_ABOVE":              true,
+	"BLOCK_MEDIUM_AND_ABOVE":           true,
+	"BLOCK_ONLY_HIGH":                  true,
+	"BLOCK_NONE":                       true,
+	"OFF":                              true,
+}
+
+// HarmCategoryPrefix is the prefix shared by all Gemini harm categories
+const HarmCategoryPrefix = "HARM_CATEGORY_"
+
+// Message types of the /v1/chat/ws WebSocket endpoint
+const (
+	WebSocketMessageCancel    = "cancel"
+	WebSocketMessageChunk     = "chunk"
+	WebSocketMessageDone      = "done"
+	WebSocketMessageCancelled = "cancelled"
+	WebSocketMessageError     = "error"
+
+	// WebSocketProtocol is the subprotocol selected by the server. Browsers cannot set headers,
+	// so they offer the API key as a second subprotocol prefixed with WebSocketAPIKeyPrefix.
+	WebSocketProtocol     = "chat"
+	WebSocketAPIKeyPrefix = "api-key."
+
+	// WebSocketWriteTimeout limits how long a single message may take to write
+	WebSocketWriteTimeout = 10 * time.Second
+)
+
 // HTTP status codes
 const (
 	StatusOK                  = 200
@@ -5,8 +5,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -14,6 +16,7 @@ import (
 	"gemini-cli-go/internal/auth"
 	"gemini-cli-go/internal/config"
 	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/metrics"
 	"gemini-cli-go/internal/models"
 	"gemini-cli-go/internal/types"
 	"gemini-cli-go/internal/utils"
@@ -23,7 +26,6 @@ import (
 type Client struct {
 	authManager *auth.AuthManager
 	config      *config.Config
-	projectID   string
 	httpClient  *http.Client
 }
 
@@ -32,65 +34,104 @@ func NewClient(config *config.Config, authManager *auth.AuthManager) *Client {
 	return &Client{
 		authManager: authManager,
 		config:      config,
-		httpClient: &http.Client{
-			Timeout: time.Duration(config.GetRequestTimeout()) * time.Second,
-		},
+		httpClient:  newHTTPClient(config),
 	}
 }
 
-// DiscoverProjectID discovers the Google Cloud project ID
-func (c *Client) DiscoverProjectID() (string, error) {
-	if c.config.GetGeminiProjectID() != "" {
-		c.projectID = c.config.GetGeminiProjectID()
-		return c.projectID, nil
+// StreamContent streams content from Gemini API
+func (c *Client) StreamContent(ctx context.Context, modelID string, systemPrompt string, messages []types.ChatMessage, options *StreamOptions) (<-chan types.StreamChunk, error) {
+	modelID, options, err := applyModelSuffixes(modelID, options)
+	if err != nil {
+		return nil, &InvalidRequestError{Err: err}
 	}
 
-	if c.projectID != "" {
-		return c.projectID, nil
+	request, err := c.buildRequest(modelID, systemPrompt, messages, options)
+	if err != nil {
+		return nil, err
 	}
 
-	// Try to discover project ID using the Code Assist API
-	response, err := c.authManager.CallEndpoint("loadCodeAssist", map[string]interface{}{
-		"cloudaicompanionProject": "default-project",
-		"metadata": map[string]interface{}{
-			"duetProject": "default-project",
-		},
-	})
-
-	if err != nil {
-		return "", fmt.Errorf("%s: %w", constants.ErrProjectDiscoveryFailed, err)
+	// Unary requests are bounded by the request timeout, streams by the maximum stream duration
+	timeout := c.config.GetStreamMaxDuration()
+	if options != nil && options.Unary {
+		timeout = time.Duration(c.config.GetRequestTimeout()) * time.Second
 	}
 
-	// Parse the response to extract project ID
-	if responseMap, ok := response.(map[string]interface{}); ok {
-		if projectID, exists := responseMap["cloudaicompanionProject"].(string); exists {
-			c.projectID = projectID
-			return projectID, nil
+	return relayChunks(ctx, timeout, func(ctx context.Context, chunkChan chan<- types.StreamChunk) {
+		// Handle thinking mode
+		if options != nil && options.EnableFakeThinking && models.SupportsThinking(modelID) {
+			if err := c.generateFakeThinking(ctx, chunkChan, messages, options.StreamThinkingAsContent); err != nil {
+				chunkChan <- types.StreamChunk{
+					Type: types.StreamChunkTypeError,
+					Data: fmt.Errorf("failed to generate thinking: %w", err),
+				}
+				return
+			}
 		}
-	}
 
-	return "", fmt.Errorf(constants.ErrProjectDiscoveryFailed)
+		// Perform stream request, failures end the stream with an error chunk
+		if err := c.performStreamRequestWithFallback(ctx, chunkChan, modelID, request, options); err != nil {
+			chunkChan <- types.StreamChunk{
+				Type: types.StreamChunkTypeError,
+				Data: err,
+			}
+		}
+	}), nil
 }
 
-// StreamContent streams content from Gemini API
-func (c *Client) StreamContent(ctx context.Context, modelID string, systemPrompt string, messages []types.ChatMessage, options *StreamOptions) (<-chan types.StreamChunk, error) {
-	if err := c.authManager.InitializeAuth(); err != nil {
-		return nil, err
-	}
+// relayChunks runs produce in its own goroutine, bounded by the timeout if it is positive, and
+// relays the chunks it sends. The returned channel is closed as soon as ctx ends because the
+// client went away, and the chunks still produced afterwards are discarded, so the producer
+// never blocks on a consumer that stopped reading.
+func relayChunks(ctx context.Context, timeout time.Duration, produce func(ctx context.Context, chunkChan chan<- types.StreamChunk)) <-chan types.StreamChunk {
+	produceCtx, cancel := withRequestDeadline(ctx, timeout)
+	produced := make(chan types.StreamChunk, 100)
+	chunkChan := make(chan types.StreamChunk)
 
-	projectID, err := c.DiscoverProjectID()
-	if err != nil {
-		return nil, err
-	}
+	go func() {
+		defer close(produced)
+		produce(produceCtx, produced)
+	}()
 
+	go func() {
+		defer cancel()
+		for chunk := range produced {
+			// The client is still waiting when only the timeout passed, tell it why the stream ended
+			if chunk.Type == types.StreamChunkTypeError && ctx.Err() == nil && errors.Is(produceCtx.Err(), context.DeadlineExceeded) {
+				chunk.Data = &UpstreamError{Message: fmt.Sprintf("request exceeded the maximum duration of %v", timeout), Err: context.DeadlineExceeded}
+			}
+
+			select {
+			case chunkChan <- chunk:
+			case <-ctx.Done():
+				close(chunkChan)
+				for range produced {
+				}
+				return
+			}
+		}
+		close(chunkChan)
+	}()
+
+	return chunkChan
+}
+
+// buildRequest converts messages and options into a Gemini generateContent request
+func (c *Client) buildRequest(modelID string, systemPrompt string, messages []types.ChatMessage, options *StreamOptions) (map[string]interface{}, error) {
 	// Extract system prompt and convert messages
-	systemPrompt, otherMessages := c.extractSystemPrompt(messages)
+	messagesSystemPrompt, otherMessages := c.extractSystemPrompt(messages)
+	if messagesSystemPrompt != "" {
+		if systemPrompt != "" {
+			systemPrompt += "\n\n"
+		}
+		systemPrompt += messagesSystemPrompt
+	}
+
 	contents, err := c.convertMessagesToGeminiFormat(otherMessages)
 	if err != nil {
-		return nil, err
+		return nil, &InvalidRequestError{Err: err}
 	}
 
-	// Add system prompt if provided
+	var systemInstruction *types.GeminiFormattedMessage
 	if systemPrompt != "" {
 		systemContent := types.GeminiFormattedMessage{
 			Role: "user",
@@ -98,66 +139,134 @@ func (c *Client) StreamContent(ctx context.Context, modelID string, systemPrompt
 				{Text: systemPrompt},
 			},
 		}
-		contents = append([]types.GeminiFormattedMessage{systemContent}, contents...)
+
+		if c.config.GetSystemPromptMode() == constants.SystemPromptModePrepend {
+			// Compatibility mode: send the system prompt as a leading user turn
+			contents = append([]types.GeminiFormattedMessage{systemContent}, contents...)
+		} else {
+			systemInstruction = &systemContent
+		}
 	}
 
+	contents = normalizeContents(contents)
+
 	// Create generation config
-	generationConfig := c.createGenerationConfig(modelID, options)
+	generationConfig, err := c.createGenerationConfig(modelID, options)
+	if err != nil {
+		return nil, &InvalidRequestError{Err: err}
+	}
 
 	// Create stream request
-	streamRequest := map[string]interface{}{
-		"model":   modelID,
-		"project": projectID,
-		"request": map[string]interface{}{
-			"contents":         contents,
-			"generationConfig": generationConfig,
-		},
+	request := map[string]interface{}{
+		"contents":         contents,
+		"generationConfig": generationConfig,
 	}
 
-	// Create channel for streaming chunks
-	chunkChan := make(chan types.StreamChunk, 100)
+	if systemInstruction != nil {
+		request["systemInstruction"] = systemInstruction
+	}
 
-	go func() {
-		defer close(chunkChan)
+	var requestSafetySettings []types.GeminiSafetySetting
+	if options != nil {
+		requestSafetySettings = options.SafetySettings
+	}
+	if safetySettings := mergeSafetySettings(c.config.GetSafetySettings(), requestSafetySettings); len(safetySettings) > 0 {
+		request["safetySettings"] = safetySettings
+	}
 
-		// Handle thinking mode
-		if options != nil && options.EnableFakeThinking && models.SupportsThinking(modelID) {
-			if err := c.generateFakeThinking(ctx, chunkChan, messages, options.StreamThinkingAsContent); err != nil {
-				chunkChan <- types.StreamChunk{
-					Type: types.StreamChunkTypeText,
-					Data: fmt.Sprintf("Error generating thinking: %v", err),
-				}
-			}
+	// Add tool declarations and tool config if provided
+	if options != nil && len(options.Tools) > 0 {
+		tools, err := convertToolsToGemini(options.Tools)
+		if err != nil {
+			return nil, &InvalidRequestError{Err: err}
 		}
+		request["tools"] = tools
 
-		// Perform stream request
-		if err := c.performStreamRequest(ctx, chunkChan, streamRequest, options); err != nil {
-			chunkChan <- types.StreamChunk{
-				Type: types.StreamChunkTypeText,
-				Data: fmt.Sprintf("Error: %v", err),
-			}
+		toolConfig, err := convertToolChoiceToGemini(options.ToolChoice)
+		if err != nil {
+			return nil, &InvalidRequestError{Err: err}
 		}
-	}()
+		if toolConfig != nil {
+			request["toolConfig"] = toolConfig
+		}
+	}
 
-	return chunkChan, nil
+	return request, nil
 }
 
 // GetCompletion gets a complete response from Gemini API (non-streaming)
 func (c *Client) GetCompletion(ctx context.Context, modelID string, messages []types.ChatMessage, options *StreamOptions) (*CompletionResult, error) {
+	// Non-streaming requests are bounded by the request timeout as a whole
+	ctx, cancel := withRequestDeadline(ctx, time.Duration(c.config.GetRequestTimeout())*time.Second)
+	defer cancel()
+
 	// Note: system prompt is handled by StreamContent
 	chunkChan, err := c.StreamContent(ctx, modelID, "", messages, options)
 	if err != nil {
 		return nil, err
 	}
 
-	var content strings.Builder
+	var candidates []*candidateBuilder
+	candidate := func(index int) *candidateBuilder {
+		for len(candidates) <= index {
+			candidates = append(candidates, &candidateBuilder{
+				result: CandidateResult{Index: len(candidates), FinishReason: constants.FinishReasonStop},
+			})
+		}
+		return candidates[index]
+	}
+	candidate(0)
+
 	var usage *types.UsageData
+	model := modelID
 
 	for chunk := range chunkChan {
 		switch chunk.Type {
 		case types.StreamChunkTypeText:
 			if text, ok := chunk.Data.(string); ok {
-				content.WriteString(text)
+				candidate(chunk.Index).content.WriteString(text)
+			}
+		case types.StreamChunkTypeThinkingContent:
+			// Thinking streamed as content is part of the answer, tags included
+			if text, ok := chunk.Data.(string); ok {
+				candidate(chunk.Index).content.WriteString(text)
+			}
+		case types.StreamChunkTypeRealThinking:
+			if text, ok := chunk.Data.(string); ok {
+				candidate(chunk.Index).reasoning.WriteString(text)
+			}
+		case types.StreamChunkTypeReasoning:
+			if reasoning, ok := chunk.Data.(types.ReasoningData); ok {
+				candidate(chunk.Index).reasoning.WriteString(reasoning.Reasoning)
+			}
+		case types.StreamChunkTypeToolCall:
+			if toolCall, ok := chunk.Data.(types.ToolCall); ok {
+				builder := candidate(chunk.Index)
+				builder.result.ToolCalls = append(builder.result.ToolCalls, toolCall)
+			}
+		case types.StreamChunkTypeAnnotations:
+			if annotations, ok := chunk.Data.([]types.Annotation); ok {
+				builder := candidate(chunk.Index)
+				builder.result.Annotations = append(builder.result.Annotations, annotations...)
+			}
+		case types.StreamChunkTypeCodeExecution:
+			if execution, ok := chunk.Data.(types.CodeExecution); ok {
+				builder := candidate(chunk.Index)
+				builder.result.CodeExecution = append(builder.result.CodeExecution, execution)
+			}
+		case types.StreamChunkTypeFinishReason:
+			if finish, ok := chunk.Data.(types.FinishData); ok {
+				builder := candidate(chunk.Index)
+				builder.result.FinishReason = finish.FinishReason
+				builder.result.SafetyRatings = finish.SafetyRatings
+			}
+		case types.StreamChunkTypeError:
+			if err, ok := chunk.Data.(error); ok {
+				return nil, err
+			}
+		case types.StreamChunkTypeModel:
+			if fallback, ok := chunk.Data.(string); ok {
+				model = fallback
 			}
 		case types.StreamChunkTypeUsage:
 			if usageData, ok := chunk.Data.(types.UsageData); ok {
@@ -166,21 +275,71 @@ func (c *Client) GetCompletion(ctx context.Context, modelID string, messages []t
 		}
 	}
 
+	// The stream also ends early when the request is cancelled, which must not look like a complete answer
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]CandidateResult, len(candidates))
+	for i, builder := range candidates {
+		results[i] = builder.result
+		results[i].Content = builder.content.String()
+		results[i].Reasoning = builder.reasoning.String()
+	}
+
 	return &CompletionResult{
-		Content: content.String(),
-		Usage:   usage,
+		Model:      model,
+		Candidates: results,
+		Usage:      usage,
 	}, nil
 }
 
+// candidateBuilder accumulates the streamed chunks of one candidate
+type candidateBuilder struct {
+	result    CandidateResult
+	content   strings.Builder
+	reasoning strings.Builder
+}
+
 // convertMessagesToGeminiFormat converts OpenAI messages to Gemini format
 func (c *Client) convertMessagesToGeminiFormat(messages []types.ChatMessage) ([]types.GeminiFormattedMessage, error) {
 	var contents []types.GeminiFormattedMessage
 
+	// Tool results only carry the tool call ID, so remember the function name of every call
+	toolNames := make(map[string]string)
+
 	for _, msg := range messages {
+		if msg.Role == "tool" {
+			part, err := convertToolResultToGeminiPart(msg, toolNames)
+			if err != nil {
+				return nil, err
+			}
+
+			// Consecutive tool results answer the same turn and must be sent together
+			if last := len(contents) - 1; last >= 0 && isFunctionResponseTurn(contents[last]) {
+				contents[last].Parts = append(contents[last].Parts, *part)
+			} else {
+				contents = append(contents, types.GeminiFormattedMessage{
+					Role:  "user",
+					Parts: []types.GeminiPart{*part},
+				})
+			}
+			continue
+		}
+
+		for _, toolCall := range msg.ToolCalls {
+			toolNames[toolCall.ID] = toolCall.Function.Name
+		}
+
 		geminiMsg, err := c.convertMessageToGeminiFormat(msg)
 		if err != nil {
 			return nil, err
 		}
+
+		// An assistant turn that only held thinking has nothing left to send, and Gemini rejects empty turns
+		if len(geminiMsg.Parts) == 0 {
+			continue
+		}
 		contents = append(contents, *geminiMsg)
 	}
 
@@ -194,22 +353,22 @@ func (c *Client) convertMessageToGeminiFormat(msg types.ChatMessage) (*types.Gem
 		role = "model"
 	}
 
-	// Handle string content
-	if text, ok := msg.Content.(string); ok {
-		return &types.GeminiFormattedMessage{
-			Role: role,
-			Parts: []types.GeminiPart{
-				{Text: text},
-			},
-		}, nil
-	}
+	var parts []types.GeminiPart
 
-	// Handle array content (multimodal)
-	if contentArray, ok := msg.Content.([]interface{}); ok {
-		var parts []types.GeminiPart
+	switch content := msg.Content.(type) {
+	case nil:
+		// Assistant messages carrying only tool calls have no content
 
-		for _, content := range contentArray {
-			if contentMap, ok := content.(map[string]interface{}); ok {
+	case string:
+		// Handle string content
+		if content != "" || len(msg.ToolCalls) == 0 {
+			parts = append(parts, types.GeminiPart{Text: content})
+		}
+
+	case []interface{}:
+		// Handle array content (multimodal)
+		for _, item := range content {
+			if contentMap, ok := item.(map[string]interface{}); ok {
 				part, err := c.convertContentToGeminiPart(contentMap)
 				if err != nil {
 					return nil, err
@@ -220,18 +379,28 @@ func (c *Client) convertMessageToGeminiFormat(msg types.ChatMessage) (*types.Gem
 			}
 		}
 
-		return &types.GeminiFormattedMessage{
-			Role:  role,
-			Parts: parts,
-		}, nil
+	default:
+		// Fallback: convert to string
+		parts = append(parts, types.GeminiPart{Text: fmt.Sprintf("%v", content)})
+	}
+
+	// Thinking echoed back by clients is not part of the answer
+	if msg.Role == "assistant" {
+		parts = separateThinking(msg, parts, c.config.KeepHistoryThinking())
+	}
+
+	// Handle tool calls made by the assistant
+	for _, toolCall := range msg.ToolCalls {
+		part, err := convertToolCallToGeminiPart(toolCall)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, *part)
 	}
 
-	// Fallback: convert to string
 	return &types.GeminiFormattedMessage{
-		Role: role,
-		Parts: []types.GeminiPart{
-			{Text: fmt.Sprintf("%v", msg.Content)},
-		},
+		Role:  role,
+		Parts: parts,
 	}, nil
 }
 
@@ -297,7 +466,7 @@ func (c *Client) convertImageURLToGeminiPart(imageURL string) (*types.GeminiPart
 }
 
 // createGenerationConfig creates generation configuration for the request
-func (c *Client) createGenerationConfig(modelID string, options *StreamOptions) map[string]interface{} {
+func (c *Client) createGenerationConfig(modelID string, options *StreamOptions) (map[string]interface{}, error) {
 	config := map[string]interface{}{
 		"temperature": constants.DefaultTemperature,
 	}
@@ -315,91 +484,281 @@ func (c *Client) createGenerationConfig(modelID string, options *StreamOptions)
 			config["topP"] = *options.TopP
 		}
 
+		if options.TopK != nil {
+			config["topK"] = *options.TopK
+		}
+
+		if len(options.StopSequences) > 0 {
+			config["stopSequences"] = options.StopSequences
+		}
+
+		if options.Seed != nil {
+			config["seed"] = *options.Seed
+		}
+
+		if options.PresencePenalty != nil {
+			config["presencePenalty"] = *options.PresencePenalty
+		}
+
+		if options.FrequencyPenalty != nil {
+			config["frequencyPenalty"] = *options.FrequencyPenalty
+		}
+
+		if options.CandidateCount != nil {
+			config["candidateCount"] = *options.CandidateCount
+		}
+
+		// Handle structured output
+		if format := options.ResponseFormat; format != nil {
+			switch format.Type {
+			case "", ResponseFormatText:
+			case ResponseFormatJSONObject:
+				config["responseMimeType"] = constants.ContentTypeJSON
+			case ResponseFormatJSONSchema:
+				if format.JSONSchema == nil || format.JSONSchema.Schema == nil {
+					return nil, fmt.Errorf("response_format.json_schema.schema is required")
+				}
+				config["responseMimeType"] = constants.ContentTypeJSON
+				config["responseSchema"] = convertJSONSchema(format.JSONSchema.Schema)
+			default:
+				return nil, fmt.Errorf("unsupported response_format type: %s", format.Type)
+			}
+		}
+
 		// Handle thinking configuration - use correct format for Gemini API
+		thinkingBudget := constants.DefaultThinkingBudget
+		if options.ThinkingBudget != nil {
+			thinkingBudget = *options.ThinkingBudget
+		} else if options.ReasoningEffort != "" {
+			budget, err := models.ReasoningEffortBudget(modelID, options.ReasoningEffort)
+			if err != nil {
+				return nil, err
+			}
+			thinkingBudget = budget
+		}
+
 		if models.SupportsThinking(modelID) {
-			thinkingBudget := constants.DefaultThinkingBudget
-			if options.ThinkingBudget != nil {
-				thinkingBudget = *options.ThinkingBudget
+			// The budget is clamped to the model's range, 0 only disables thinking where the model allows it
+			thinkingBudget = models.ClampThinkingBudget(modelID, thinkingBudget)
+
+			config["thinkingConfig"] = map[string]interface{}{
+				"thinkingBudget":  thinkingBudget,
+				"includeThoughts": options.EnableRealThinking && thinkingBudget != constants.DisabledThinkingBudget,
 			}
-			
-			// Validate thinking budget (can't be 0 for thinking models)
-			if thinkingBudget == 0 {
-				thinkingBudget = constants.DefaultThinkingBudget
+		}
+		// Don't add thinkingConfig for non-thinking models
+	}
+
+	return config, nil
+}
+
+// performStreamRequestWithFallback performs the stream request, moving on to the model's
+// fallbacks when it is exhausted or overloaded before anything was sent to the client
+func (c *Client) performStreamRequestWithFallback(ctx context.Context, chunkChan chan<- types.StreamChunk, modelID string, request map[string]interface{}, options *StreamOptions) error {
+	chain := models.GetFallbackChain(modelID)
+
+	var err error
+	for i, model := range chain {
+		if i > 0 {
+			// The generation config depends on the model, e.g. for thinking support
+			generationConfig, configErr := c.createGenerationConfig(model, options)
+			if configErr != nil {
+				// Report the original failure rather than the fallback's configuration error
+				return err
 			}
+			request["generationConfig"] = generationConfig
 
-			if options.EnableRealThinking {
-				// Enable thinking with proper structure
-				config["thinkingConfig"] = map[string]interface{}{
-					"thinkingBudget":  thinkingBudget,
-					"includeThoughts": true,
-				}
-			} else {
-				// Disable thinking visibility but still provide valid budget
-				config["thinkingConfig"] = map[string]interface{}{
-					"thinkingBudget":  thinkingBudget,
-					"includeThoughts": false,
-				}
+			metrics.Inc(metrics.ModelFallbacks)
+			log.Printf("Model %s unavailable, falling back to %s: %v", chain[i-1], model, err)
+			chunkChan <- types.StreamChunk{
+				Type: types.StreamChunkTypeModel,
+				Data: model,
 			}
 		}
-		// Don't add thinkingConfig for non-thinking models
+
+		var sent bool
+		sent, err = c.performStreamRequest(ctx, chunkChan, model, request, options)
+		if err == nil || sent || !shouldFallback(err) || ctx.Err() != nil {
+			return err
+		}
 	}
 
-	return config
+	return err
 }
 
-// performStreamRequest performs the actual stream request
-func (c *Client) performStreamRequest(ctx context.Context, chunkChan chan<- types.StreamChunk, streamRequest map[string]interface{}, options *StreamOptions) error {
-	return c.performStreamRequestWithRetry(ctx, chunkChan, streamRequest, options, false)
+// performStreamRequest performs the actual stream request, retrying transient
+// failures as long as nothing has been sent to the client. It reports whether
+// any chunk was sent.
+func (c *Client) performStreamRequest(ctx context.Context, chunkChan chan<- types.StreamChunk, modelID string, request map[string]interface{}, options *StreamOptions) (bool, error) {
+	policy := newRetryPolicy(c.config)
+	start := time.Now()
+	retry := 0
+	failovers := 0
+
+	for {
+		metrics.Inc(metrics.UpstreamRequests)
+
+		state := &StreamingContext{}
+		err := c.performAccountRequest(ctx, chunkChan, modelID, request, options, state)
+		if err == nil {
+			return true, nil
+		}
+
+		reason, retryable := retryReason(err)
+		if !retryable || state.HasSentChunks || ctx.Err() != nil {
+			return state.HasSentChunks, err
+		}
+
+		// A rate limited account is cooling down for the model, so move on to the next one without
+		// waiting. Once all of them are, the pool reports when the first one is available again.
+		if reason == "rate_limited" && failovers < len(c.authManager.Accounts()) {
+			failovers++
+			if c.authManager.HasAvailableAccount(modelID) {
+				metrics.Inc(metrics.AccountFailovers)
+				log.Printf("Upstream request rate limited, switching account: %v", err)
+			}
+			continue
+		}
+
+		var retryAfter time.Duration
+		var upstreamErr *UpstreamError
+		if errors.As(err, &upstreamErr) {
+			retryAfter = upstreamErr.RetryAfter
+		}
+
+		delay := policy.Backoff(retry, retryAfter)
+		if retry >= policy.MaxRetries || time.Since(start)+delay > policy.MaxElapsed {
+			if retry > 0 {
+				metrics.Inc(metrics.UpstreamRetriesExhausted)
+				log.Printf("Upstream request failed after %d retries: %v", retry, err)
+			}
+			return false, err
+		}
+
+		metrics.Inc(metrics.UpstreamRetries)
+		metrics.Inc("upstream_retries_" + reason)
+		log.Printf("Upstream request failed (%s), retry %d/%d in %v: %v", reason, retry+1, policy.MaxRetries, delay, err)
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return false, err
+		}
+		retry++
+	}
 }
 
-// performStreamRequestWithRetry performs stream request with retry logic
-func (c *Client) performStreamRequestWithRetry(ctx context.Context, chunkChan chan<- types.StreamChunk, streamRequest map[string]interface{}, options *StreamOptions, isRetry bool) error {
+// performAccountRequest sends the request with the next account from the pool and records the outcome on it
+func (c *Client) performAccountRequest(ctx context.Context, chunkChan chan<- types.StreamChunk, modelID string, request map[string]interface{}, options *StreamOptions, state *StreamingContext) error {
+	account, err := c.authManager.Acquire(modelID)
+	if err != nil {
+		var exhaustedErr *auth.PoolExhaustedError
+		if errors.As(err, &exhaustedErr) {
+			return &UpstreamError{
+				StatusCode: http.StatusTooManyRequests,
+				Status:     "RESOURCE_EXHAUSTED",
+				Message:    err.Error(),
+				RetryAfter: exhaustedErr.RetryAfter,
+			}
+		}
+		return err
+	}
+
+	if err := account.InitializeAuth(); err != nil {
+		account.MarkFailed(err)
+		return err
+	}
+
+	var streamRequest map[string]interface{}
+	if options != nil && options.NativeMethod == NativeMethodCountTokens {
+		streamRequest = countTokensEnvelope(modelID, request)
+	} else {
+		projectID, err := account.DiscoverProjectID()
+		if err != nil {
+			account.MarkFailed(err)
+			return err
+		}
+
+		streamRequest = map[string]interface{}{
+			"model":   modelID,
+			"project": projectID,
+			"request": request,
+		}
+	}
+
+	err = c.performStreamRequestWithRetry(ctx, account, chunkChan, streamRequest, options, state, false)
+	if err != nil {
+		var upstreamErr *UpstreamError
+		if errors.As(err, &upstreamErr) && upstreamErr.StatusCode == http.StatusTooManyRequests {
+			account.MarkRateLimited(modelID, upstreamErr.RetryAfter)
+			log.Printf("Account %s is rate limited for %s, cooling down", account.Label(), modelID)
+		} else if ctx.Err() == nil {
+			account.MarkFailed(err)
+		}
+	}
+
+	return err
+}
+
+// performStreamRequestWithRetry performs stream request, retrying once with a fresh token on 401
+func (c *Client) performStreamRequestWithRetry(ctx context.Context, account *auth.Account, chunkChan chan<- types.StreamChunk, streamRequest map[string]interface{}, options *StreamOptions, state *StreamingContext, isRetry bool) error {
 	bodyBytes, err := json.Marshal(streamRequest)
 	if err != nil {
 		return fmt.Errorf("failed to marshal stream request: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/%s:streamGenerateContent?alt=sse", constants.CodeAssistEndpoint, constants.CodeAssistAPIVersion)
+	url := fmt.Sprintf("%s/%s:%s", c.config.GetCodeAssistEndpoint(), constants.CodeAssistAPIVersion, upstreamMethod(options))
+
+	// Streams are cancelled when the first byte takes too long or they go idle
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var watchdog *streamWatchdog
+	if !isUnaryNativeMethod(options) {
+		watchdog = newStreamWatchdog(cancel, c.config.GetUpstreamFirstByteTimeout(), c.config.GetStreamIdleTimeout())
+		defer watchdog.stop()
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(bodyBytes))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", constants.ContentTypeJSON)
-	req.Header.Set("Authorization", constants.BearerPrefix+c.authManager.GetAccessToken())
+	req.Header.Set("Authorization", constants.BearerPrefix+account.GetAccessToken())
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
+		return watchdog.check(&UpstreamError{Message: "request failed", Err: err})
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusUnauthorized && !isRetry {
-		c.authManager.ClearTokenCache()
-		if err := c.authManager.InitializeAuth(); err != nil {
+		watchdog.stop()
+		account.ClearTokenCache()
+		if err := account.InitializeAuth(); err != nil {
 			return err
 		}
-		return c.performStreamRequestWithRetry(ctx, chunkChan, streamRequest, options, true)
+		return c.performStreamRequestWithRetry(ctx, account, chunkChan, streamRequest, options, state, true)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		// Read the error response body for debugging
 		bodyBytes, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return fmt.Errorf("stream request failed with status %d (could not read error body: %v)", resp.StatusCode, err)
+			log.Printf("Failed to read error body for status %d: %v", resp.StatusCode, err)
 		}
-		return fmt.Errorf("stream request failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+		return newUpstreamError(resp, bodyBytes)
+	}
+
+	if isUnaryNativeMethod(options) {
+		return c.readNativeResponse(chunkChan, resp.Body, state)
 	}
 
-	return c.parseSSEStream(ctx, chunkChan, resp.Body, options)
+	return watchdog.check(c.parseSSEStream(ctx, chunkChan, watchdog.watch(resp.Body), options, state))
 }
 
 // parseSSEStream parses the Server-Sent Events stream
-func (c *Client) parseSSEStream(ctx context.Context, chunkChan chan<- types.StreamChunk, body io.ReadCloser, options *StreamOptions) error {
+func (c *Client) parseSSEStream(ctx context.Context, chunkChan chan<- types.StreamChunk, body io.ReadCloser, options *StreamOptions, state *StreamingContext) error {
 	scanner := bufio.NewScanner(body)
 	var buffer strings.Builder
-	hasClosedThinking := false
-	hasStartedThinking := false
 
 	for scanner.Scan() {
 		select {
@@ -412,9 +771,11 @@ func (c *Client) parseSSEStream(ctx context.Context, chunkChan chan<- types.Stre
 		
 		if line == "" {
 			if buffer.Len() > 0 {
-				if err := c.processSSEData(chunkChan, buffer.String(), options, &hasStartedThinking, &hasClosedThinking); err != nil {
+				if err := c.processSSEData(chunkChan, buffer.String(), options, state); err != nil {
 					return err
 				}
+				// Once an event has been processed the request can no longer be retried
+				state.HasSentChunks = true
 				buffer.Reset()
 			}
 			continue
@@ -425,40 +786,83 @@ func (c *Client) parseSSEStream(ctx context.Context, chunkChan chan<- types.Stre
 		}
 	}
 
-	return scanner.Err()
+	if err := scanner.Err(); err != nil {
+		return &UpstreamError{Message: "failed to read stream", Err: err}
+	}
+	return nil
 }
 
 // processSSEData processes SSE data and sends chunks
-func (c *Client) processSSEData(chunkChan chan<- types.StreamChunk, data string, options *StreamOptions, hasStartedThinking *bool, hasClosedThinking *bool) error {
+func (c *Client) processSSEData(chunkChan chan<- types.StreamChunk, data string, options *StreamOptions, state *StreamingContext) error {
 	if data == "[DONE]" {
 		return nil
 	}
 
+	if options != nil && options.NativeMethod != "" {
+		return sendNativeResponse(chunkChan, []byte(data))
+	}
+
 	var geminiResp types.GeminiResponse
 	if err := json.Unmarshal([]byte(data), &geminiResp); err != nil {
-		return fmt.Errorf("failed to parse SSE data: %w", err)
+		return &UpstreamError{Message: "failed to parse SSE data", Err: err}
 	}
 
 	if geminiResp.Response == nil {
 		return nil
 	}
 
-	// Process candidates
+	// A blocked prompt produces no candidates
+	if feedback := geminiResp.Response.PromptFeedback; feedback != nil && feedback.BlockReason != "" {
+		return newPromptBlockedError(feedback)
+	}
+
+	// Process candidates, each chunk carries the index of the candidate it belongs to
 	for _, candidate := range geminiResp.Response.Candidates {
+		candidateState := state.Candidate(candidate.Index)
+
 		if candidate.Content != nil {
 			for _, part := range candidate.Content.Parts {
-				if err := c.processPart(chunkChan, part, options, hasStartedThinking, hasClosedThinking); err != nil {
+				if err := c.processPart(chunkChan, candidate.Index, part, options, candidateState); err != nil {
 					return err
 				}
 			}
 		}
+
+		if candidate.GroundingMetadata != nil {
+			if annotations := groundingAnnotations(candidate.GroundingMetadata, candidateState); len(annotations) > 0 {
+				chunkChan <- types.StreamChunk{
+					Type:  types.StreamChunkTypeAnnotations,
+					Index: candidate.Index,
+					Data:  annotations,
+				}
+			}
+		}
+
+		// Ratings are reported on each chunk, the latest ones cover the whole candidate
+		if len(candidate.SafetyRatings) > 0 {
+			candidateState.SafetyRatings = candidate.SafetyRatings
+		}
+
+		if candidate.FinishReason != "" {
+			c.closeThinking(chunkChan, candidate.Index, candidateState)
+			chunkChan <- types.StreamChunk{
+				Type:  types.StreamChunkTypeFinishReason,
+				Index: candidate.Index,
+				Data: types.FinishData{
+					FinishReason:  mapFinishReason(candidate.FinishReason, candidateState.ToolCallCount > 0),
+					SafetyRatings: candidateState.SafetyRatings,
+				},
+			}
+		}
 	}
 
-	// Process usage metadata
-	if geminiResp.Response.UsageMetadata != nil {
+	// Process usage metadata, candidatesTokenCount already covers all candidates
+	if metadata := geminiResp.Response.UsageMetadata; metadata != nil {
 		usage := types.UsageData{
-			InputTokens:  geminiResp.Response.UsageMetadata.PromptTokenCount,
-			OutputTokens: geminiResp.Response.UsageMetadata.CandidatesTokenCount,
+			InputTokens:     metadata.PromptTokenCount + metadata.ToolUsePromptTokenCount,
+			OutputTokens:    metadata.CandidatesTokenCount + metadata.ThoughtsTokenCount,
+			ReasoningTokens: metadata.ThoughtsTokenCount,
+			CachedTokens:    metadata.CachedContentTokenCount,
 		}
 		chunkChan <- types.StreamChunk{
 			Type: types.StreamChunkTypeUsage,
@@ -469,26 +873,59 @@ func (c *Client) processSSEData(chunkChan chan<- types.StreamChunk, data string,
 	return nil
 }
 
-// processPart processes a Gemini part and sends appropriate chunks
-func (c *Client) processPart(chunkChan chan<- types.StreamChunk, part types.GeminiPart, options *StreamOptions, hasStartedThinking *bool, hasClosedThinking *bool) error {
+// processPart processes a Gemini part of the candidate with the given index and sends appropriate chunks
+func (c *Client) processPart(chunkChan chan<- types.StreamChunk, index int, part types.GeminiPart, options *StreamOptions, state *CandidateContext) error {
+	// Handle function calls
+	if part.FunctionCall != nil {
+		// Gemini has no switch for parallel calls, so only surface the first one when disabled
+		if options != nil && options.ParallelToolCalls != nil && !*options.ParallelToolCalls && state.ToolCallCount > 0 {
+			return nil
+		}
+
+		c.closeThinking(chunkChan, index, state)
+
+		toolCall, err := convertFunctionCallToToolCall(part.FunctionCall)
+		if err != nil {
+			return err
+		}
+		state.ToolCallCount++
+
+		chunkChan <- types.StreamChunk{
+			Type:  types.StreamChunkTypeToolCall,
+			Index: index,
+			Data:  toolCall,
+		}
+		return nil
+	}
+
+	// Handle code execution
+	if part.ExecutableCode != nil || part.CodeExecutionResult != nil {
+		c.closeThinking(chunkChan, index, state)
+		sendCodeExecution(chunkChan, index, part, options, state)
+		return nil
+	}
+
 	// Handle thinking content
 	if part.Thought && part.Text != "" {
 		if options != nil && options.StreamThinkingAsContent {
-			if !*hasStartedThinking {
+			if !state.HasStartedThinking {
 				chunkChan <- types.StreamChunk{
-					Type: types.StreamChunkTypeThinkingContent,
-					Data: constants.ThinkingOpenTag,
+					Type:  types.StreamChunkTypeThinkingContent,
+					Index: index,
+					Data:  constants.ThinkingOpenTag,
 				}
-				*hasStartedThinking = true
+				state.HasStartedThinking = true
 			}
 			chunkChan <- types.StreamChunk{
-				Type: types.StreamChunkTypeThinkingContent,
-				Data: part.Text,
+				Type:  types.StreamChunkTypeThinkingContent,
+				Index: index,
+				Data:  part.Text,
 			}
 		} else {
 			chunkChan <- types.StreamChunk{
-				Type: types.StreamChunkTypeRealThinking,
-				Data: part.Text,
+				Type:  types.StreamChunkTypeRealThinking,
+				Index: index,
+				Data:  part.Text,
 			}
 		}
 		return nil
@@ -497,23 +934,46 @@ func (c *Client) processPart(chunkChan chan<- types.StreamChunk, part types.Gemi
 	// Handle regular text content
 	if part.Text != "" && !part.Thought {
 		// Close thinking tag if needed
-		if *hasStartedThinking && !*hasClosedThinking {
-			chunkChan <- types.StreamChunk{
-				Type: types.StreamChunkTypeThinkingContent,
-				Data: constants.ThinkingCloseTag,
-			}
-			*hasClosedThinking = true
-		}
+		c.closeThinking(chunkChan, index, state)
+		state.text.WriteString(part.Text)
 
 		chunkChan <- types.StreamChunk{
-			Type: types.StreamChunkTypeText,
-			Data: part.Text,
+			Type:  types.StreamChunkTypeText,
+			Index: index,
+			Data:  part.Text,
 		}
 	}
 
 	return nil
 }
 
+// mapFinishReason maps a Gemini finish reason to an OpenAI finish_reason
+func mapFinishReason(reason string, hasToolCalls bool) string {
+	switch reason {
+	case "MAX_TOKENS":
+		return constants.FinishReasonLength
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return constants.FinishReasonContentFilter
+	}
+
+	if hasToolCalls {
+		return constants.FinishReasonToolCalls
+	}
+	return constants.FinishReasonStop
+}
+
+// closeThinking closes the thinking tag of a candidate if thinking was streamed as content
+func (c *Client) closeThinking(chunkChan chan<- types.StreamChunk, index int, state *CandidateContext) {
+	if state.HasStartedThinking && !state.HasClosedThinking {
+		chunkChan <- types.StreamChunk{
+			Type:  types.StreamChunkTypeThinkingContent,
+			Index: index,
+			Data:  constants.ThinkingCloseTag,
+		}
+		state.HasClosedThinking = true
+	}
+}
+
 // generateFakeThinking generates fake thinking output
 func (c *Client) generateFakeThinking(ctx context.Context, chunkChan chan<- types.StreamChunk, messages []types.ChatMessage, streamAsContent bool) error {
 	// Get the last user message
@@ -605,33 +1065,35 @@ func (c *Client) splitIntoChunks(text string, chunkSize int) []string {
 	return chunks
 }
 
-// extractSystemPrompt extracts system prompt from messages
+// extractSystemPrompt extracts system and developer messages, combined in order, from messages
 func (c *Client) extractSystemPrompt(messages []types.ChatMessage) (string, []types.ChatMessage) {
-	var systemPrompt string
+	var systemParts []string
 	var otherMessages []types.ChatMessage
 
 	for _, msg := range messages {
-		if msg.Role == "system" {
-			if text, ok := msg.Content.(string); ok {
-				systemPrompt = text
-			} else if contentArray, ok := msg.Content.([]interface{}); ok {
-				// Extract text content from array
-				var textParts []string
-				for _, content := range contentArray {
-					if contentMap, ok := content.(map[string]interface{}); ok {
-						if contentType, ok := contentMap["type"].(string); ok && contentType == "text" {
-							if text, ok := contentMap["text"].(string); ok {
-								textParts = append(textParts, text)
-							}
-						}
-					}
-				}
-				systemPrompt = strings.Join(textParts, " ")
+		if msg.Role == "system" || msg.Role == "developer" {
+			if text := messageText(msg.Content); text != "" {
+				systemParts = append(systemParts, text)
 			}
 		} else {
 			otherMessages = append(otherMessages, msg)
 		}
 	}
 
-	return systemPrompt, otherMessages
+	return strings.Join(systemParts, "\n\n"), otherMessages
+}
+
+// normalizeContents makes sure the conversation starts with a user turn, as required by Gemini
+func normalizeContents(contents []types.GeminiFormattedMessage) []types.GeminiFormattedMessage {
+	if len(contents) > 0 && contents[0].Role == "user" {
+		return contents
+	}
+
+	placeholder := types.GeminiFormattedMessage{
+		Role: "user",
+		Parts: []types.GeminiPart{
+			{Text: constants.ConversationStartPlaceholder},
+		},
+	}
+	return append([]types.GeminiFormattedMessage{placeholder}, contents...)
 }
\ No newline at end of file
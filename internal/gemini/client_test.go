@@ -0,0 +1,183 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"gemini-cli-go/internal/config"
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractSystemPrompt(t *testing.T) {
+	client := &Client{}
+
+	messages := []types.ChatMessage{
+		{
+			Role:    "system",
+			Content: "You are a helpful assistant",
+		},
+		{
+			Role:    "user",
+			Content: "Hello",
+		},
+		{
+			Role:    "assistant",
+			Content: "Hi there!",
+		},
+	}
+
+	systemPrompt, otherMessages := client.extractSystemPrompt(messages)
+
+	assert.Equal(t, "You are a helpful assistant", systemPrompt)
+	assert.Len(t, otherMessages, 2)
+	assert.Equal(t, "user", otherMessages[0].Role)
+	assert.Equal(t, "assistant", otherMessages[1].Role)
+}
+
+func TestBuildRequestSystemPrompt(t *testing.T) {
+	userTurn := types.GeminiFormattedMessage{Role: "user", Parts: []types.GeminiPart{{Text: "Hello"}}}
+	modelTurn := types.GeminiFormattedMessage{Role: "model", Parts: []types.GeminiPart{{Text: "Hi there!"}}}
+	placeholder := types.GeminiFormattedMessage{Role: "user", Parts: []types.GeminiPart{{Text: constants.ConversationStartPlaceholder}}}
+	systemTurn := func(text string) types.GeminiFormattedMessage {
+		return types.GeminiFormattedMessage{Role: "user", Parts: []types.GeminiPart{{Text: text}}}
+	}
+
+	tests := []struct {
+		name         string
+		mode         string
+		systemPrompt string
+		messages     []types.ChatMessage
+		instruction  string
+		contents     []types.GeminiFormattedMessage
+	}{
+		{
+			name: "system and developer messages are combined in order",
+			messages: []types.ChatMessage{
+				{Role: "system", Content: "Be brief."},
+				{Role: "user", Content: "Hello"},
+				{Role: "developer", Content: "Answer in French."},
+				{Role: "system", Content: "Never guess."},
+			},
+			instruction: "Be brief.\n\nAnswer in French.\n\nNever guess.",
+			contents:    []types.GeminiFormattedMessage{userTurn},
+		},
+		{
+			name:         "explicit system prompt comes first",
+			systemPrompt: "You are a translator.",
+			messages: []types.ChatMessage{
+				{Role: "developer", Content: "Be brief."},
+				{Role: "user", Content: "Hello"},
+			},
+			instruction: "You are a translator.\n\nBe brief.",
+			contents:    []types.GeminiFormattedMessage{userTurn},
+		},
+		{
+			name: "assistant first history starts with a placeholder user turn",
+			messages: []types.ChatMessage{
+				{Role: "system", Content: "Be brief."},
+				{Role: "assistant", Content: "Hi there!"},
+				{Role: "user", Content: "Hello"},
+			},
+			instruction: "Be brief.",
+			contents:    []types.GeminiFormattedMessage{placeholder, modelTurn, userTurn},
+		},
+		{
+			name: "prepend mode sends the system prompt as the first user turn",
+			mode: constants.SystemPromptModePrepend,
+			messages: []types.ChatMessage{
+				{Role: "system", Content: "Be brief."},
+				{Role: "developer", Content: "Answer in French."},
+				{Role: "user", Content: "Hello"},
+			},
+			contents: []types.GeminiFormattedMessage{systemTurn("Be brief.\n\nAnswer in French."), userTurn},
+		},
+		{
+			name: "prepend mode needs no placeholder before an assistant turn",
+			mode: constants.SystemPromptModePrepend,
+			messages: []types.ChatMessage{
+				{Role: "system", Content: "Be brief."},
+				{Role: "assistant", Content: "Hi there!"},
+				{Role: "user", Content: "Hello"},
+			},
+			contents: []types.GeminiFormattedMessage{systemTurn("Be brief."), modelTurn, userTurn},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{config: &config.Config{Environment: types.Environment{SystemPromptMode: tt.mode}}}
+			request, err := client.buildRequest("gemini-2.5-flash", tt.systemPrompt, tt.messages, nil)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.contents, request["contents"])
+			if tt.instruction == "" {
+				assert.NotContains(t, request, "systemInstruction")
+			} else {
+				assert.Equal(t, systemTurn(tt.instruction), *request["systemInstruction"].(*types.GeminiFormattedMessage))
+			}
+		})
+	}
+}
+
+func TestGetCompletionThinkingModes(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   *StreamOptions
+		content   []string
+		reasoning []string
+	}{
+		{
+			name:      "real thinking",
+			options:   &StreamOptions{EnableRealThinking: true},
+			content:   []string{"Hi!"},
+			reasoning: []string{"Pondering"},
+		},
+		{
+			name:    "thinking as content",
+			options: &StreamOptions{EnableRealThinking: true, StreamThinkingAsContent: true},
+			content: []string{constants.ThinkingOpenTag + "Pondering" + constants.ThinkingCloseTag + "Hi!"},
+		},
+		{
+			name:      "fake thinking",
+			options:   &StreamOptions{EnableFakeThinking: true},
+			content:   []string{"Hi!"},
+			reasoning: []string{"Analyzing the request: \"Hello\"", "structured answer"},
+		},
+		{
+			name:    "fake thinking as content",
+			options: &StreamOptions{EnableFakeThinking: true, StreamThinkingAsContent: true},
+			content: []string{constants.ThinkingOpenTag, "Analyzing the request: \"Hello\"", "Hi!"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestClient(t, types.Environment{}, func(w http.ResponseWriter, model string, attempt int) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				if !tt.options.EnableFakeThinking {
+					fmt.Fprint(w, "data: {\"response\":{\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Pondering\",\"thought\":true}]}}]}}\n\n")
+				}
+				fmt.Fprint(w, "data: {\"response\":{\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Hi!\"}]},\"finishReason\":\"STOP\"}]}}\n\n")
+			})
+
+			result, err := client.GetCompletion(context.Background(), "gemini-2.5-flash", userMessage, tt.options)
+			require.NoError(t, err)
+			candidate := result.Candidates[0]
+			for _, text := range tt.content {
+				assert.Contains(t, candidate.Content, text)
+			}
+			if tt.reasoning == nil {
+				assert.Empty(t, candidate.Reasoning)
+			}
+			for _, text := range tt.reasoning {
+				assert.Contains(t, candidate.Reasoning, text)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package gemini
+
+import (
+	"strings"
+
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/types"
+)
+
+// sendCodeExecution sends an executableCode or codeExecutionResult part, as a structured chunk or
+// as a fenced markdown block of text depending on the code execution format
+func sendCodeExecution(chunkChan chan<- types.StreamChunk, index int, part types.GeminiPart, options *StreamOptions, state *CandidateContext) {
+	execution := convertCodeExecution(part)
+
+	if options != nil && options.CodeExecutionFormat == constants.CodeExecutionFormatStructured {
+		chunkChan <- types.StreamChunk{
+			Type:  types.StreamChunkTypeCodeExecution,
+			Index: index,
+			Data:  execution,
+		}
+		return
+	}
+
+	// The block is part of the answer text, though Gemini's grounding offsets do not count it
+	markdown := renderCodeExecutionMarkdown(execution)
+	state.rendered = append(state.rendered, renderedBlock{offset: state.text.Len() - state.renderedLength(), length: len(markdown)})
+	state.text.WriteString(markdown)
+
+	chunkChan <- types.StreamChunk{
+		Type:  types.StreamChunkTypeText,
+		Index: index,
+		Data:  markdown,
+	}
+}
+
+// convertCodeExecution converts a code execution part to its structured form
+func convertCodeExecution(part types.GeminiPart) types.CodeExecution {
+	if code := part.ExecutableCode; code != nil {
+		return types.CodeExecution{
+			Type:     constants.CodeExecutionTypeExecutableCode,
+			Language: strings.ToLower(code.Language),
+			Code:     code.Code,
+		}
+	}
+
+	result := part.CodeExecutionResult
+	return types.CodeExecution{
+		Type:    constants.CodeExecutionTypeResult,
+		Outcome: result.Outcome,
+		Output:  result.Output,
+	}
+}
+
+// renderCodeExecutionMarkdown renders code as a fenced block tagged with its language, and a result
+// as an output block, preceded by the outcome when the code did not run successfully
+func renderCodeExecutionMarkdown(execution types.CodeExecution) string {
+	var b strings.Builder
+	b.WriteString("\n\n")
+
+	if execution.Type == constants.CodeExecutionTypeExecutableCode {
+		b.WriteString("```" + execution.Language + "\n")
+		b.WriteString(execution.Code)
+	} else {
+		if execution.Outcome != constants.CodeExecutionOutcomeOK {
+			b.WriteString("Outcome: " + execution.Outcome + "\n\n")
+		}
+		b.WriteString("```output\n")
+		b.WriteString(execution.Output)
+	}
+
+	if !strings.HasSuffix(b.String(), "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString("```\n\n")
+	return b.String()
+}
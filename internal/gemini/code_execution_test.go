@@ -0,0 +1,115 @@
+package gemini
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/types"
+)
+
+// codeExecutionEvents are the SSE events of an answer that runs code, then comments on the result
+var codeExecutionEvents = []string{
+	`{"response":{"candidates":[{"content":{"parts":[{"executableCode":{"language":"PYTHON","code":"print(sum(range(101)))"}}]}}]}}`,
+	`{"response":{"candidates":[{"content":{"parts":[{"codeExecutionResult":{"outcome":"OUTCOME_OK","output":"5050\n"}}]}}]}}`,
+	`{"response":{"candidates":[{"content":{"parts":[{"text":"The sum is 5050."}]},"finishReason":"STOP"}]}}`,
+}
+
+func TestProcessSSEDataCodeExecutionMarkdown(t *testing.T) {
+	chunks := processEvents(t, nil, codeExecutionEvents...)
+
+	assert.Empty(t, chunksOfType(chunks, types.StreamChunkTypeCodeExecution))
+	assert.Equal(t, []interface{}{
+		"\n\n```python\nprint(sum(range(101)))\n```\n\n",
+		"\n\n```output\n5050\n```\n\n",
+		"The sum is 5050.",
+	}, chunksOfType(chunks, types.StreamChunkTypeText))
+}
+
+func TestProcessSSEDataCodeExecutionStructured(t *testing.T) {
+	options := &StreamOptions{CodeExecutionFormat: constants.CodeExecutionFormatStructured}
+	chunks := processEvents(t, options, codeExecutionEvents...)
+
+	assert.Equal(t, []interface{}{
+		types.CodeExecution{Type: constants.CodeExecutionTypeExecutableCode, Language: "python", Code: "print(sum(range(101)))"},
+		types.CodeExecution{Type: constants.CodeExecutionTypeResult, Outcome: constants.CodeExecutionOutcomeOK, Output: "5050\n"},
+	}, chunksOfType(chunks, types.StreamChunkTypeCodeExecution))
+	assert.Equal(t, []interface{}{"The sum is 5050."}, chunksOfType(chunks, types.StreamChunkTypeText))
+}
+
+func TestProcessSSEDataCodeExecutionGroundingOffsets(t *testing.T) {
+	events := []string{
+		`{"response":{"candidates":[{"content":{"parts":[{"text":"Run: "},{"executableCode":{"language":"PYTHON","code":"print(1)"}}]}}]}}`,
+		`{"response":{"candidates":[{"content":{"parts":[{"text":"Café wins."}]},"finishReason":"STOP","groundingMetadata":{
+			"groundingChunks":[{"web":{"uri":"https://example.com/cafe","title":"example.com"}}],
+			"groundingSupports":[{"segment":{"startIndex":5,"endIndex":10},"groundingChunkIndices":[0]}]
+		}}]}}`,
+	}
+	markdown := "\n\n```python\nprint(1)\n```\n\n"
+
+	tests := []struct {
+		name    string
+		options *StreamOptions
+		start   int
+	}{
+		{name: "markdown", options: nil, start: 5 + len(markdown)},
+		{name: "structured", options: &StreamOptions{CodeExecutionFormat: constants.CodeExecutionFormatStructured}, start: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := processEvents(t, tt.options, events...)
+
+			// The citation covers "Café" in the text sent, rendered code included
+			annotations := chunksOfType(chunks, types.StreamChunkTypeAnnotations)
+			require.Len(t, annotations, 1)
+			citation := annotations[0].([]types.Annotation)[0].URLCitation
+			assert.Equal(t, tt.start, citation.StartIndex)
+			assert.Equal(t, tt.start+4, citation.EndIndex)
+
+			var text strings.Builder
+			for _, data := range chunksOfType(chunks, types.StreamChunkTypeText) {
+				text.WriteString(data.(string))
+			}
+			assert.Equal(t, "Café", string([]rune(text.String())[citation.StartIndex:citation.EndIndex]))
+		})
+	}
+}
+
+func TestRenderCodeExecutionMarkdown(t *testing.T) {
+	tests := []struct {
+		name      string
+		execution types.CodeExecution
+		want      string
+	}{
+		{
+			name:      "code without trailing newline",
+			execution: types.CodeExecution{Type: constants.CodeExecutionTypeExecutableCode, Language: "python", Code: "x = 1"},
+			want:      "\n\n```python\nx = 1\n```\n\n",
+		},
+		{
+			name:      "successful result",
+			execution: types.CodeExecution{Type: constants.CodeExecutionTypeResult, Outcome: constants.CodeExecutionOutcomeOK, Output: "1\n"},
+			want:      "\n\n```output\n1\n```\n\n",
+		},
+		{
+			name:      "failed result shows the outcome",
+			execution: types.CodeExecution{Type: constants.CodeExecutionTypeResult, Outcome: "OUTCOME_FAILED", Output: "NameError: y"},
+			want:      "\n\nOutcome: OUTCOME_FAILED\n\n```output\nNameError: y\n```\n\n",
+		},
+		{
+			name:      "empty output",
+			execution: types.CodeExecution{Type: constants.CodeExecutionTypeResult, Outcome: constants.CodeExecutionOutcomeOK},
+			want:      "\n\n```output\n```\n\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, renderCodeExecutionMarkdown(tt.execution))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gemini-cli-go/internal/types"
+)
+
+// countTokensResponse represents the response of the Code Assist countTokens method
+type countTokensResponse struct {
+	TotalTokens int `json:"totalTokens"`
+}
+
+// CountTokens counts the prompt tokens of a conversation without generating a response
+func (c *Client) CountTokens(ctx context.Context, modelID string, systemPrompt string, messages []types.ChatMessage, options *StreamOptions) (int, error) {
+	modelID, options, err := applyModelSuffixes(modelID, options)
+	if err != nil {
+		return 0, &InvalidRequestError{Err: err}
+	}
+
+	request, err := c.buildRequest(modelID, systemPrompt, messages, options)
+	if err != nil {
+		return 0, err
+	}
+
+	// countTokens only accepts contents, so the system instruction is counted as a leading user turn
+	contents, _ := request["contents"].([]types.GeminiFormattedMessage)
+	if systemInstruction, ok := request["systemInstruction"].(*types.GeminiFormattedMessage); ok {
+		contents = append([]types.GeminiFormattedMessage{*systemInstruction}, contents...)
+	}
+
+	chunkChan, err := c.ForwardNative(ctx, modelID, NativeMethodCountTokens, map[string]interface{}{
+		"contents": contents,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var result countTokensResponse
+	for chunk := range chunkChan {
+		switch chunk.Type {
+		case types.StreamChunkTypeRaw:
+			if raw, ok := chunk.Data.(json.RawMessage); ok {
+				if err := json.Unmarshal(raw, &result); err != nil {
+					return 0, &UpstreamError{Message: "failed to parse count tokens response", Err: err}
+				}
+			}
+		case types.StreamChunkTypeError:
+			if err, ok := chunk.Data.(error); ok {
+				return 0, err
+			}
+			return 0, fmt.Errorf("%v", chunk.Data)
+		}
+	}
+
+	return result.TotalTokens, nil
+}
@@ -0,0 +1,156 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/types"
+)
+
+// PromptBlockedError is returned when Gemini refuses to process the prompt
+type PromptBlockedError struct {
+	Reason        string
+	Message       string
+	SafetyRatings []types.GeminiSafetyRating
+}
+
+// Error implements the error interface
+func (e *PromptBlockedError) Error() string {
+	message := fmt.Sprintf("%s: %s", constants.ErrPromptBlocked, e.Reason)
+
+	var categories []string
+	for _, rating := range e.SafetyRatings {
+		if rating.Blocked {
+			categories = append(categories, rating.Category)
+		}
+	}
+	if len(categories) > 0 {
+		message += fmt.Sprintf(" (%s)", strings.Join(categories, ", "))
+	}
+
+	if e.Message != "" {
+		message += ": " + e.Message
+	}
+
+	return message
+}
+
+// newPromptBlockedError creates an error from Gemini prompt feedback
+func newPromptBlockedError(feedback *types.GeminiPromptFeedback) *PromptBlockedError {
+	return &PromptBlockedError{
+		Reason:        feedback.BlockReason,
+		Message:       feedback.BlockReasonMessage,
+		SafetyRatings: feedback.SafetyRatings,
+	}
+}
+
+// InvalidRequestError is returned when a request cannot be translated into a Gemini request
+type InvalidRequestError struct {
+	Err error
+}
+
+// Error implements the error interface
+func (e *InvalidRequestError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *InvalidRequestError) Unwrap() error {
+	return e.Err
+}
+
+// UpstreamError is returned when the Code Assist API fails or cannot be reached
+type UpstreamError struct {
+	// StatusCode is the HTTP status returned by the API, 0 if no response was received
+	StatusCode int
+	// Status is the Google RPC status, such as RESOURCE_EXHAUSTED
+	Status string
+	// Message describes the failure
+	Message string
+	// RetryAfter is the delay requested by the API before retrying, if any
+	RetryAfter time.Duration
+	// Err is the underlying transport error, if any
+	Err error
+}
+
+// Error implements the error interface
+func (e *UpstreamError) Error() string {
+	if e.StatusCode == 0 {
+		if e.Err != nil {
+			return fmt.Sprintf("%s: %s: %v", constants.ErrStreamRequestFailed, e.Message, e.Err)
+		}
+		return fmt.Sprintf("%s: %s", constants.ErrStreamRequestFailed, e.Message)
+	}
+	return fmt.Sprintf("%s with status %d: %s", constants.ErrStreamRequestFailed, e.StatusCode, e.Message)
+}
+
+// Unwrap returns the underlying transport error
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// IsTimeout reports whether the upstream request timed out
+func (e *UpstreamError) IsTimeout() bool {
+	if e.StatusCode == http.StatusGatewayTimeout || e.Status == "DEADLINE_EXCEEDED" {
+		return true
+	}
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// googleErrorResponse represents the error body returned by Google APIs
+type googleErrorResponse struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			Type       string `json:"@type"`
+			RetryDelay string `json:"retryDelay"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// newUpstreamError creates an error from a failed Code Assist API response
+func newUpstreamError(resp *http.Response, body []byte) *UpstreamError {
+	upstreamErr := &UpstreamError{
+		StatusCode: resp.StatusCode,
+		Message:    strings.TrimSpace(string(body)),
+	}
+
+	// Google APIs return a JSON error body, fall back to the raw body otherwise
+	var googleErr googleErrorResponse
+	if err := json.Unmarshal(body, &googleErr); err == nil && googleErr.Error.Message != "" {
+		upstreamErr.Status = googleErr.Error.Status
+		upstreamErr.Message = googleErr.Error.Message
+		for _, detail := range googleErr.Error.Details {
+			if strings.HasSuffix(detail.Type, "google.rpc.RetryInfo") {
+				if delay, err := time.ParseDuration(detail.RetryDelay); err == nil {
+					upstreamErr.RetryAfter = delay
+				}
+			}
+		}
+	}
+	if upstreamErr.Message == "" {
+		upstreamErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	if upstreamErr.RetryAfter == 0 {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			upstreamErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return upstreamErr
+}
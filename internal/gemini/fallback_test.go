@@ -0,0 +1,44 @@
+package gemini
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCompletionFallsBackOnRateLimitedAccount(t *testing.T) {
+	env := retryEnv
+	env.AccountCooldown = 60
+
+	client, upstream := newTestClient(t, env, func(w http.ResponseWriter, model string, attempt int) {
+		if model == "gemini-2.5-pro" {
+			writeGoogleError(w, http.StatusTooManyRequests, "RESOURCE_EXHAUSTED")
+			return
+		}
+		writeSSEText(w, "Hi!")
+	})
+
+	result, err := client.GetCompletion(context.Background(), "gemini-2.5-pro", userMessage, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "gemini-2.5-flash", result.Model)
+	assert.Equal(t, "Hi!", result.Candidates[0].Content)
+	assert.Equal(t, []string{"gemini-2.5-pro", "gemini-2.5-flash"}, upstream.requests())
+
+	statuses := client.authManager.AccountStatuses()
+	require.Len(t, statuses, 1)
+	assert.Contains(t, statuses[0].CooldownModels, "gemini-2.5-pro")
+	assert.NotContains(t, statuses[0].CooldownModels, "gemini-2.5-flash")
+}
+
+func TestGetCompletionDoesNotFallBackOnClientErrors(t *testing.T) {
+	client, upstream := newTestClient(t, retryEnv, func(w http.ResponseWriter, model string, attempt int) {
+		writeGoogleError(w, http.StatusBadRequest, "INVALID_ARGUMENT")
+	})
+
+	_, err := client.GetCompletion(context.Background(), "gemini-2.5-pro", userMessage, nil)
+	require.Error(t, err)
+	assert.Equal(t, []string{"gemini-2.5-pro"}, upstream.requests())
+}
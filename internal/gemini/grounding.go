@@ -0,0 +1,70 @@
+package gemini
+
+import (
+	"unicode/utf8"
+
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/types"
+)
+
+// groundingAnnotations converts the grounding metadata of a candidate to URL citations, one per
+// supported segment and source. Gemini's byte offsets are turned into character offsets of the
+// answer text received so far, and citations already sent for the candidate are skipped.
+func groundingAnnotations(metadata *types.GeminiGroundingMetadata, state *CandidateContext) []types.Annotation {
+	text := state.text.String()
+
+	var annotations []types.Annotation
+	for _, support := range metadata.GroundingSupports {
+		start := characterOffset(text, state.answerOffset(support.Segment.StartIndex, true))
+		end := characterOffset(text, state.answerOffset(support.Segment.EndIndex, false))
+
+		for _, chunkIndex := range support.GroundingChunkIndices {
+			if chunkIndex < 0 || chunkIndex >= len(metadata.GroundingChunks) {
+				continue
+			}
+			web := metadata.GroundingChunks[chunkIndex].Web
+			if web == nil || web.URI == "" {
+				continue
+			}
+
+			citation := types.URLCitation{StartIndex: start, EndIndex: end, URL: web.URI, Title: web.Title}
+			if state.cited[citation] {
+				continue
+			}
+			if state.cited == nil {
+				state.cited = make(map[types.URLCitation]bool)
+			}
+			state.cited[citation] = true
+
+			annotations = append(annotations, types.Annotation{
+				Type:        constants.AnnotationTypeURLCitation,
+				URLCitation: &citation,
+			})
+		}
+	}
+	return annotations
+}
+
+// answerOffset converts a byte offset into Gemini's text to a byte offset into the answer text, which
+// also holds the rendered blocks. A block rendered at the offset precedes a segment starting there
+// and follows a segment ending there.
+func (cc *CandidateContext) answerOffset(offset int, start bool) int {
+	shifted := offset
+	for _, block := range cc.rendered {
+		if block.offset < offset || (start && block.offset == offset) {
+			shifted += block.length
+		}
+	}
+	return shifted
+}
+
+// characterOffset converts a byte offset into text to a character offset, clamped to the text
+func characterOffset(text string, offset int) int {
+	if offset <= 0 {
+		return 0
+	}
+	if offset > len(text) {
+		offset = len(text)
+	}
+	return utf8.RuneCountInString(text[:offset])
+}
@@ -0,0 +1,135 @@
+package gemini
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gemini-cli-go/internal/config"
+	"gemini-cli-go/internal/types"
+)
+
+// processEvents runs SSE events through processSSEData and returns the chunks they produce
+func processEvents(t *testing.T, options *StreamOptions, events ...string) []types.StreamChunk {
+	t.Helper()
+	client := &Client{config: &config.Config{}}
+	state := &StreamingContext{}
+	chunkChan := make(chan types.StreamChunk, 100)
+	for _, event := range events {
+		require.NoError(t, client.processSSEData(chunkChan, event, options, state))
+	}
+	close(chunkChan)
+
+	var chunks []types.StreamChunk
+	for chunk := range chunkChan {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// chunksOfType returns the data of the chunks with the given type
+func chunksOfType(chunks []types.StreamChunk, chunkType types.StreamChunkType) []interface{} {
+	var data []interface{}
+	for _, chunk := range chunks {
+		if chunk.Type == chunkType {
+			data = append(data, chunk.Data)
+		}
+	}
+	return data
+}
+
+func TestCharacterOffset(t *testing.T) {
+	text := "Café – 東京"
+
+	tests := []struct {
+		name   string
+		offset int
+		want   int
+	}{
+		{name: "start", offset: 0, want: 0},
+		{name: "negative", offset: -3, want: 0},
+		{name: "ascii prefix", offset: 3, want: 3},
+		{name: "after a two byte character", offset: 5, want: 4},
+		{name: "after a three byte dash", offset: 9, want: 6},
+		{name: "end", offset: len(text), want: 9},
+		{name: "beyond the end", offset: len(text) + 10, want: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, characterOffset(text, tt.offset))
+		})
+	}
+}
+
+func TestGroundingAnnotations(t *testing.T) {
+	// Gemini offsets count bytes of the UTF-8 text, é takes two
+	var metadata types.GeminiGroundingMetadata
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"groundingChunks": [
+			{"web": {"uri": "https://example.com/cafe", "title": "example.com"}},
+			{},
+			{"web": {"uri": "https://example.org/tea", "title": "example.org"}}
+		],
+		"groundingSupports": [
+			{"segment": {"endIndex": 18}, "groundingChunkIndices": [0, 1, 7, -1]},
+			{"segment": {"startIndex": 19, "endIndex": 27}, "groundingChunkIndices": [2, 0]}
+		]
+	}`), &metadata))
+
+	state := &CandidateContext{}
+	state.text.WriteString("Café prices rose. Tea too.")
+
+	annotations := groundingAnnotations(&metadata, state)
+	require.Len(t, annotations, 3)
+	assert.Equal(t, "url_citation", annotations[0].Type)
+	assert.Equal(t, types.URLCitation{StartIndex: 0, EndIndex: 17, URL: "https://example.com/cafe", Title: "example.com"}, *annotations[0].URLCitation)
+	assert.Equal(t, types.URLCitation{StartIndex: 18, EndIndex: 26, URL: "https://example.org/tea", Title: "example.org"}, *annotations[1].URLCitation)
+	assert.Equal(t, types.URLCitation{StartIndex: 18, EndIndex: 26, URL: "https://example.com/cafe", Title: "example.com"}, *annotations[2].URLCitation)
+
+	// Gemini repeats the metadata on later chunks, citations already sent are not sent again
+	assert.Empty(t, groundingAnnotations(&metadata, state))
+}
+
+func TestProcessSSEDataGrounding(t *testing.T) {
+	chunks := processEvents(t, nil,
+		`{"response":{"candidates":[{"content":{"parts":[{"text":"Café prices rose."}]}}]}}`,
+		`{"response":{"candidates":[{"content":{"parts":[{"text":" Tea too."}]},"finishReason":"STOP","groundingMetadata":{
+			"groundingChunks":[{"web":{"uri":"https://example.org/tea","title":"example.org"}}],
+			"groundingSupports":[{"segment":{"startIndex":19,"endIndex":27},"groundingChunkIndices":[0]}]
+		}}]}}`,
+	)
+
+	annotations := chunksOfType(chunks, types.StreamChunkTypeAnnotations)
+	require.Len(t, annotations, 1)
+	assert.Equal(t, []types.Annotation{{
+		Type:        "url_citation",
+		URLCitation: &types.URLCitation{StartIndex: 18, EndIndex: 26, URL: "https://example.org/tea", Title: "example.org"},
+	}}, annotations[0])
+}
+
+func TestApplyModelSuffixes(t *testing.T) {
+	function := types.Tool{Type: "function", Function: &types.ToolFunction{Name: "lookup"}}
+	options := &StreamOptions{Tools: []types.Tool{function}}
+
+	model, got, err := applyModelSuffixes("gemini-2.5-pro", options)
+	require.NoError(t, err)
+	assert.Equal(t, "gemini-2.5-pro", model)
+	assert.Same(t, options, got)
+
+	model, got, err = applyModelSuffixes("gemini-2.5-pro:search:code_execution", options)
+	require.NoError(t, err)
+	assert.Equal(t, "gemini-2.5-pro", model)
+	assert.Equal(t, []types.Tool{function, {Type: ToolTypeGoogleSearch}, {Type: ToolTypeCodeExecution}}, got.Tools)
+	assert.Len(t, options.Tools, 1, "the options of the caller are left untouched")
+
+	model, got, err = applyModelSuffixes("gemini-2.5-flash:url_context", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "gemini-2.5-flash", model)
+	assert.Equal(t, []types.Tool{{Type: ToolTypeURLContext}}, got.Tools)
+
+	_, _, err = applyModelSuffixes("gemini-2.5-flash:unknown", nil)
+	assert.EqualError(t, err, "unsupported model suffix: unknown")
+}
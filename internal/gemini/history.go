@@ -0,0 +1,74 @@
+package gemini
+
+import (
+	"regexp"
+	"strings"
+
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/types"
+)
+
+// thinkingBlockPattern matches the thinking block the proxy puts at the start of assistant content:
+// <thinking> from STREAM_THINKING_AS_CONTENT and <think> from the think_tags reasoning format.
+// Tags elsewhere in the content are part of the answer and left alone.
+var thinkingBlockPattern = regexp.MustCompile(`^\s*(?:` +
+	thinkingBlock(constants.ThinkingOpenTag, constants.ThinkingCloseTag) + `|` +
+	thinkingBlock(constants.ThinkOpenTag, constants.ThinkCloseTag) + `)\s*`)
+
+// thinkingBlock returns the pattern of a block between two tags, capturing its content
+func thinkingBlock(openTag, closeTag string) string {
+	return regexp.QuoteMeta(strings.TrimSpace(openTag)) + `(?s:(.*?))` + regexp.QuoteMeta(strings.TrimSpace(closeTag))
+}
+
+// extractThinking removes the leading thinking block from assistant text, returning the remaining text and the thought
+func extractThinking(text string) (string, []string) {
+	match := thinkingBlockPattern.FindStringSubmatch(text)
+	if match == nil {
+		return text, nil
+	}
+
+	var thoughts []string
+	if thought := strings.TrimSpace(match[1] + match[2]); thought != "" {
+		thoughts = append(thoughts, thought)
+	}
+	return text[len(match[0]):], thoughts
+}
+
+// separateThinking removes the thinking clients echo back in an assistant turn, from reasoning
+// fields and the thinking block leading its text. The thoughts are kept as leading thought parts
+// when keep is set and dropped otherwise.
+func separateThinking(msg types.ChatMessage, parts []types.GeminiPart, keep bool) []types.GeminiPart {
+	var thoughts []string
+	for _, reasoning := range []string{msg.ReasoningContent, msg.Reasoning} {
+		if reasoning = strings.TrimSpace(reasoning); reasoning != "" {
+			thoughts = append(thoughts, reasoning)
+		}
+	}
+
+	result := make([]types.GeminiPart, 0, len(parts))
+	leading := true
+	for _, part := range parts {
+		if part.Text == "" || part.Thought || !leading {
+			result = append(result, part)
+			continue
+		}
+		leading = false
+
+		text, partThoughts := extractThinking(part.Text)
+		thoughts = append(thoughts, partThoughts...)
+		if text == "" {
+			continue
+		}
+		part.Text = text
+		result = append(result, part)
+	}
+
+	if keep && len(thoughts) > 0 {
+		thoughtParts := make([]types.GeminiPart, 0, len(thoughts))
+		for _, thought := range thoughts {
+			thoughtParts = append(thoughtParts, types.GeminiPart{Text: thought, Thought: true})
+		}
+		result = append(thoughtParts, result...)
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package gemini
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gemini-cli-go/internal/config"
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/types"
+)
+
+func TestExtractThinking(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		want     string
+		thoughts []string
+	}{
+		{
+			name:     "think tags",
+			text:     constants.ThinkOpenTag + "Let me see." + constants.ThinkCloseTag + "The answer is 42.",
+			want:     "The answer is 42.",
+			thoughts: []string{"Let me see."},
+		},
+		{
+			name:     "thinking tags",
+			text:     constants.ThinkingOpenTag + "Let me see." + constants.ThinkingCloseTag + "The answer is 42.",
+			want:     "The answer is 42.",
+			thoughts: []string{"Let me see."},
+		},
+		{
+			name:     "whitespace trimmed by the client",
+			text:     "\n<think>Let me see.</think>The answer is 42.",
+			want:     "The answer is 42.",
+			thoughts: []string{"Let me see."},
+		},
+		{
+			name: "no thinking",
+			text: "The answer is 42.",
+			want: "The answer is 42.",
+		},
+		{
+			name: "tags inside the answer are kept",
+			text: "Wrap it in <think>tags</think> like this.",
+			want: "Wrap it in <think>tags</think> like this.",
+		},
+		{
+			name:     "only the leading block is removed",
+			text:     "<think>First.</think>\n\nUse <thinking>x</thinking> in the prompt.",
+			want:     "Use <thinking>x</thinking> in the prompt.",
+			thoughts: []string{"First."},
+		},
+		{
+			name: "empty block",
+			text: "<think>\n</think>\n\nHi",
+			want: "Hi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, thoughts := extractThinking(tt.text)
+			assert.Equal(t, tt.want, text)
+			assert.Equal(t, tt.thoughts, thoughts)
+		})
+	}
+}
+
+func TestSeparateThinking(t *testing.T) {
+	msg := types.ChatMessage{Role: "assistant", ReasoningContent: "From the field."}
+	parts := []types.GeminiPart{{Text: "<think>From the tags.</think>Answer <think>kept</think>"}}
+
+	assert.Equal(t, []types.GeminiPart{{Text: "Answer <think>kept</think>"}}, separateThinking(msg, parts, false))
+	assert.Equal(t, []types.GeminiPart{
+		{Text: "From the field.", Thought: true},
+		{Text: "From the tags.", Thought: true},
+		{Text: "Answer <think>kept</think>"},
+	}, separateThinking(msg, parts, true))
+
+	// A turn that only held thinking is left without parts
+	assert.Empty(t, separateThinking(types.ChatMessage{Role: "assistant"}, []types.GeminiPart{{Text: "<think>Hmm.</think>"}}, false))
+}
+
+func TestConvertMessagesDropsThinkingOnlyTurns(t *testing.T) {
+	client := &Client{config: &config.Config{}}
+	messages := []types.ChatMessage{
+		{Role: "user", Content: "Hello"},
+		{Role: "assistant", Content: "<think>\nHmm.\n</think>\n\n"},
+		{Role: "user", Content: "Are you there?"},
+		{Role: "assistant", Content: "<think>Calling a tool.</think>", ToolCalls: []types.ToolCall{{
+			ID:       "call_1",
+			Type:     "function",
+			Function: types.ToolCallFunction{Name: "lookup", Arguments: `{"q":"x"}`},
+		}}},
+	}
+
+	contents, err := client.convertMessagesToGeminiFormat(messages)
+	require.NoError(t, err)
+	require.Len(t, contents, 3)
+	assert.Equal(t, "user", contents[0].Role)
+	assert.Equal(t, "user", contents[1].Role)
+	assert.Equal(t, "model", contents[2].Role)
+	require.Len(t, contents[2].Parts, 1)
+	assert.NotNil(t, contents[2].Parts[0].FunctionCall)
+
+	// No part serializes as an empty object
+	data, err := json.Marshal(contents)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "{}")
+}
@@ -0,0 +1,106 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"gemini-cli-go/internal/types"
+)
+
+// Native Gemini methods that can be forwarded to the Code Assist API
+const (
+	NativeMethodGenerateContent       = "generateContent"
+	NativeMethodStreamGenerateContent = "streamGenerateContent"
+	NativeMethodCountTokens           = "countTokens"
+)
+
+// ForwardNative sends a native Gemini request to the Code Assist API and returns the
+// unwrapped responses as raw chunks. The model is used as requested, without fallbacks.
+func (c *Client) ForwardNative(ctx context.Context, modelID string, method string, request map[string]interface{}) (<-chan types.StreamChunk, error) {
+	switch method {
+	case NativeMethodGenerateContent, NativeMethodStreamGenerateContent, NativeMethodCountTokens:
+	default:
+		return nil, &InvalidRequestError{Err: fmt.Errorf("unsupported method: %s", method)}
+	}
+
+	// Unary methods are bounded by the request timeout, streams by the maximum stream duration
+	timeout := c.config.GetStreamMaxDuration()
+	if method != NativeMethodStreamGenerateContent {
+		timeout = time.Duration(c.config.GetRequestTimeout()) * time.Second
+	}
+
+	return relayChunks(ctx, timeout, func(ctx context.Context, chunkChan chan<- types.StreamChunk) {
+		options := &StreamOptions{NativeMethod: method}
+		if _, err := c.performStreamRequest(ctx, chunkChan, modelID, request, options); err != nil {
+			chunkChan <- types.StreamChunk{
+				Type: types.StreamChunkTypeError,
+				Data: err,
+			}
+		}
+	}), nil
+}
+
+// upstreamMethod returns the Code Assist method, with query string, a request is sent to
+func upstreamMethod(options *StreamOptions) string {
+	if isUnaryNativeMethod(options) {
+		return options.NativeMethod
+	}
+	return "streamGenerateContent?alt=sse"
+}
+
+// isUnaryNativeMethod reports whether the request is forwarded to a method that answers with a single JSON body
+func isUnaryNativeMethod(options *StreamOptions) bool {
+	return options != nil && (options.NativeMethod == NativeMethodGenerateContent || options.NativeMethod == NativeMethodCountTokens)
+}
+
+// countTokensEnvelope wraps a native countTokens request, which carries the model instead of a project
+func countTokensEnvelope(modelID string, request map[string]interface{}) map[string]interface{} {
+	countRequest := make(map[string]interface{}, len(request)+1)
+	for key, value := range request {
+		countRequest[key] = value
+	}
+	countRequest["model"] = "models/" + modelID
+
+	return map[string]interface{}{
+		"request": countRequest,
+	}
+}
+
+// readNativeResponse reads a unary Code Assist response and sends it as a raw chunk
+func (c *Client) readNativeResponse(chunkChan chan<- types.StreamChunk, body io.Reader, state *StreamingContext) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return &UpstreamError{Message: "failed to read response", Err: err}
+	}
+
+	if err := sendNativeResponse(chunkChan, data); err != nil {
+		return err
+	}
+	state.HasSentChunks = true
+	return nil
+}
+
+// sendNativeResponse unwraps the response field of a Code Assist response and sends it as a raw chunk
+func sendNativeResponse(chunkChan chan<- types.StreamChunk, data []byte) error {
+	var envelope struct {
+		Response json.RawMessage `json:"response"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return &UpstreamError{Message: "failed to parse response", Err: err}
+	}
+
+	// countTokens responses are not wrapped
+	raw := envelope.Response
+	if len(raw) == 0 {
+		raw = json.RawMessage(data)
+	}
+
+	chunkChan <- types.StreamChunk{
+		Type: types.StreamChunkTypeRaw,
+		Data: raw,
+	}
+	return nil
+}
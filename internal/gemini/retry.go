@@ -0,0 +1,106 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"gemini-cli-go/internal/config"
+)
+
+// RetryPolicy controls how failed upstream requests are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// InitialDelay is the backoff before the first retry
+	InitialDelay time.Duration
+	// MaxDelay caps the exponential backoff
+	MaxDelay time.Duration
+	// MaxElapsed caps the total time spent on a request including retries
+	MaxElapsed time.Duration
+}
+
+// newRetryPolicy creates a retry policy from the configuration
+func newRetryPolicy(cfg *config.Config) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:   cfg.GetRetryMaxRetries(),
+		InitialDelay: cfg.GetRetryInitialDelay(),
+		MaxDelay:     cfg.GetRetryMaxDelay(),
+		MaxElapsed:   cfg.GetRetryMaxElapsed(),
+	}
+}
+
+// Backoff returns the delay before the given retry (starting at 0).
+// An upstream requested delay is honoured as is, otherwise the delay grows
+// exponentially with jitter in the upper half of the interval.
+func (p RetryPolicy) Backoff(retry int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	delay := p.InitialDelay
+	for i := 0; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryReason reports why an error can be retried, or false if it cannot
+func retryReason(err error) (string, bool) {
+	var upstreamErr *UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		return "", false
+	}
+
+	switch {
+	case upstreamErr.StatusCode == 0:
+		if errors.Is(upstreamErr.Err, context.Canceled) {
+			return "", false
+		}
+		return "connection_error", true
+	case upstreamErr.StatusCode == http.StatusTooManyRequests:
+		return "rate_limited", true
+	case upstreamErr.StatusCode >= http.StatusInternalServerError:
+		return "server_error", true
+	}
+
+	return "", false
+}
+
+// shouldFallback reports whether an error means the model is exhausted or overloaded,
+// so the request may move on to a fallback model
+func shouldFallback(err error) bool {
+	var upstreamErr *UpstreamError
+	if !errors.As(err, &upstreamErr) {
+		return false
+	}
+
+	switch upstreamErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	}
+	return upstreamErr.Status == "RESOURCE_EXHAUSTED" || upstreamErr.Status == "UNAVAILABLE"
+}
+
+// sleepContext waits for the delay or until the context is done
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,108 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"gemini-cli-go/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		name       string
+		retry      int
+		retryAfter time.Duration
+		min, max   time.Duration
+	}{
+		{name: "first retry", retry: 0, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{name: "grows exponentially", retry: 2, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{name: "capped at the maximum delay", retry: 10, min: 500 * time.Millisecond, max: time.Second},
+		{name: "upstream delay is honoured", retry: 0, retryAfter: 3 * time.Second, min: 3 * time.Second, max: 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				delay := policy.Backoff(tt.retry, tt.retryAfter)
+				assert.GreaterOrEqual(t, delay, tt.min)
+				assert.LessOrEqual(t, delay, tt.max)
+			}
+		})
+	}
+
+	assert.Zero(t, RetryPolicy{}.Backoff(3, 0))
+}
+
+func TestRetryReason(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		reason    string
+		retryable bool
+	}{
+		{name: "connection error", err: &UpstreamError{Message: "request failed", Err: errors.New("connection refused")}, reason: "connection_error", retryable: true},
+		{name: "cancelled request", err: &UpstreamError{Message: "request failed", Err: context.Canceled}},
+		{name: "rate limited", err: &UpstreamError{StatusCode: http.StatusTooManyRequests}, reason: "rate_limited", retryable: true},
+		{name: "server error", err: &UpstreamError{StatusCode: http.StatusServiceUnavailable}, reason: "server_error", retryable: true},
+		{name: "client error", err: &UpstreamError{StatusCode: http.StatusBadRequest}},
+		{name: "other error", err: errors.New("invalid request")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, retryable := retryReason(tt.err)
+			assert.Equal(t, tt.reason, reason)
+			assert.Equal(t, tt.retryable, retryable)
+		})
+	}
+}
+
+// retryEnv retries quickly, up to three times
+var retryEnv = types.Environment{RetryMaxRetries: 3, RetryInitialDelayMs: 1, RetryMaxDelayMs: 2, RetryMaxElapsedMs: 5000}
+
+func TestGetCompletionRetriesTransientFailures(t *testing.T) {
+	client, upstream := newTestClient(t, retryEnv, func(w http.ResponseWriter, model string, attempt int) {
+		if attempt < 2 {
+			writeGoogleError(w, http.StatusInternalServerError, "INTERNAL")
+			return
+		}
+		writeSSEText(w, "Hi!")
+	})
+
+	result, err := client.GetCompletion(context.Background(), "gemini-2.5-flash", userMessage, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi!", result.Candidates[0].Content)
+	assert.Len(t, upstream.requests(), 3)
+}
+
+func TestGetCompletionGivesUpAfterMaxRetries(t *testing.T) {
+	client, upstream := newTestClient(t, retryEnv, func(w http.ResponseWriter, model string, attempt int) {
+		writeGoogleError(w, http.StatusInternalServerError, "INTERNAL")
+	})
+
+	_, err := client.GetCompletion(context.Background(), "gemini-2.5-flash", userMessage, nil)
+	var upstreamErr *UpstreamError
+	require.ErrorAs(t, err, &upstreamErr)
+	assert.Equal(t, http.StatusInternalServerError, upstreamErr.StatusCode)
+	assert.Len(t, upstream.requests(), 4)
+}
+
+func TestGetCompletionDoesNotRetryClientErrors(t *testing.T) {
+	client, upstream := newTestClient(t, retryEnv, func(w http.ResponseWriter, model string, attempt int) {
+		writeGoogleError(w, http.StatusBadRequest, "INVALID_ARGUMENT")
+	})
+
+	_, err := client.GetCompletion(context.Background(), "gemini-2.5-flash", userMessage, nil)
+	var upstreamErr *UpstreamError
+	require.ErrorAs(t, err, &upstreamErr)
+	assert.Equal(t, http.StatusBadRequest, upstreamErr.StatusCode)
+	assert.Len(t, upstream.requests(), 1)
+}
@@ -0,0 +1,26 @@
+package gemini
+
+import (
+	"gemini-cli-go/internal/types"
+)
+
+// mergeSafetySettings overlays per-request safety settings on the deployment defaults.
+// A request setting replaces the default for the same harm category.
+func mergeSafetySettings(defaults, overrides []types.GeminiSafetySetting) []types.GeminiSafetySetting {
+	if len(overrides) == 0 {
+		return defaults
+	}
+
+	merged := make([]types.GeminiSafetySetting, 0, len(defaults)+len(overrides))
+	index := make(map[string]int, len(defaults)+len(overrides))
+	for _, setting := range append(append([]types.GeminiSafetySetting{}, defaults...), overrides...) {
+		if i, exists := index[setting.Category]; exists {
+			merged[i] = setting
+			continue
+		}
+		index[setting.Category] = len(merged)
+		merged = append(merged, setting)
+	}
+
+	return merged
+}
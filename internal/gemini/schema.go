@@ -0,0 +1,267 @@
+package gemini
+
+import (
+	"strings"
+)
+
+// maxSchemaRefDepth limits how deep recursive $ref definitions are inlined,
+// since Gemini schemas cannot reference definitions
+const maxSchemaRefDepth = 8
+
+// supportedSchemaKeys lists the JSON Schema keywords accepted by Gemini's OpenAPI schema subset
+var supportedSchemaKeys = map[string]bool{
+	"type":             true,
+	"format":           true,
+	"title":            true,
+	"description":      true,
+	"nullable":         true,
+	"enum":             true,
+	"properties":       true,
+	"required":         true,
+	"items":            true,
+	"anyOf":            true,
+	"minItems":         true,
+	"maxItems":         true,
+	"minLength":        true,
+	"maxLength":        true,
+	"minProperties":    true,
+	"maxProperties":    true,
+	"minimum":          true,
+	"maximum":          true,
+	"pattern":          true,
+	"propertyOrdering": true,
+}
+
+// supportedStringFormats lists the string formats Gemini accepts
+var supportedStringFormats = map[string]bool{
+	"enum":      true,
+	"date-time": true,
+}
+
+// convertJSONSchema converts a JSON Schema object into the schema subset accepted by Gemini
+func convertJSONSchema(schema map[string]interface{}) map[string]interface{} {
+	return convertSchemaNode(schema, schemaDefinitions(schema), 0)
+}
+
+// convertSchemaNode converts a single schema node, resolving references against defs
+func convertSchemaNode(schema map[string]interface{}, defs map[string]interface{}, depth int) map[string]interface{} {
+	if schema == nil {
+		return nil
+	}
+
+	schema = normalizeSchemaNode(schema, defs, depth)
+	if schema == nil {
+		return nil
+	}
+	if _, isRef := schema["$ref"]; isRef {
+		depth++
+	}
+
+	result := make(map[string]interface{})
+
+	for key, value := range schema {
+		if !supportedSchemaKeys[key] {
+			continue
+		}
+
+		switch key {
+		case "type":
+			// JSON Schema allows a list of types such as ["string", "null"]
+			if typeList, ok := value.([]interface{}); ok {
+				for _, t := range typeList {
+					if typeName, ok := t.(string); ok {
+						if typeName == "null" {
+							result["nullable"] = true
+						} else if _, exists := result["type"]; !exists {
+							result["type"] = typeName
+						}
+					}
+				}
+				continue
+			}
+			result[key] = value
+
+		case "properties":
+			if properties, ok := value.(map[string]interface{}); ok {
+				converted := make(map[string]interface{}, len(properties))
+				for name, property := range properties {
+					if propertySchema, ok := property.(map[string]interface{}); ok {
+						if convertedProperty := convertSchemaNode(propertySchema, defs, depth); convertedProperty != nil {
+							converted[name] = convertedProperty
+						}
+					}
+				}
+				result[key] = converted
+			}
+
+		case "items":
+			if items, ok := value.(map[string]interface{}); ok {
+				if convertedItems := convertSchemaNode(items, defs, depth); convertedItems != nil {
+					result[key] = convertedItems
+				}
+			}
+
+		case "anyOf":
+			if variants, ok := value.([]interface{}); ok {
+				var converted []interface{}
+				for _, variant := range variants {
+					if variantSchema, ok := variant.(map[string]interface{}); ok {
+						// A {"type": "null"} branch is expressed as nullable in Gemini
+						if variantSchema["type"] == "null" {
+							result["nullable"] = true
+							continue
+						}
+						if convertedVariant := convertSchemaNode(variantSchema, defs, depth); convertedVariant != nil {
+							converted = append(converted, convertedVariant)
+						}
+					}
+				}
+				if len(converted) == 1 {
+					// Keywords declared next to anyOf take precedence over the single branch
+					for k, v := range converted[0].(map[string]interface{}) {
+						if _, exists := schema[k]; !exists {
+							result[k] = v
+						}
+					}
+				} else if len(converted) > 1 {
+					result[key] = converted
+				}
+			}
+
+		case "enum":
+			// Gemini only accepts string enums
+			if values, ok := value.([]interface{}); ok && allStrings(values) {
+				result[key] = values
+			}
+
+		default:
+			result[key] = value
+		}
+	}
+
+	if format, ok := result["format"].(string); ok && result["type"] == "string" && !supportedStringFormats[format] {
+		delete(result, "format")
+	}
+
+	return result
+}
+
+// normalizeSchemaNode rewrites keywords Gemini lacks ($ref, allOf, oneOf, const) into supported ones
+func normalizeSchemaNode(schema map[string]interface{}, defs map[string]interface{}, depth int) map[string]interface{} {
+	normalized := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		normalized[key] = value
+	}
+
+	// Inline references, letting sibling keywords such as description override the definition
+	if ref, ok := normalized["$ref"].(string); ok {
+		resolved := resolveSchemaRef(ref, defs)
+		if resolved == nil || depth >= maxSchemaRefDepth {
+			return nil
+		}
+		for key, value := range resolved {
+			if _, exists := normalized[key]; !exists {
+				normalized[key] = value
+			}
+		}
+		if nestedRef, ok := resolved["$ref"]; ok {
+			normalized["$ref"] = nestedRef
+			return normalizeSchemaNode(normalized, defs, depth+1)
+		}
+	}
+
+	// oneOf has no Gemini equivalent, anyOf is the closest match
+	if oneOf, ok := normalized["oneOf"]; ok {
+		if _, exists := normalized["anyOf"]; !exists {
+			normalized["anyOf"] = oneOf
+		}
+		delete(normalized, "oneOf")
+	}
+
+	// allOf is merged into a single schema
+	if allOf, ok := normalized["allOf"].([]interface{}); ok {
+		delete(normalized, "allOf")
+		for _, item := range allOf {
+			if subSchema, ok := item.(map[string]interface{}); ok {
+				subSchema = normalizeSchemaNode(subSchema, defs, depth+1)
+				mergeSchemas(normalized, subSchema)
+			}
+		}
+	}
+
+	// const becomes a single value enum
+	if constValue, ok := normalized["const"]; ok {
+		if _, exists := normalized["enum"]; !exists {
+			normalized["enum"] = []interface{}{constValue}
+		}
+		delete(normalized, "const")
+	}
+
+	return normalized
+}
+
+// mergeSchemas merges the properties and required fields of source into target
+func mergeSchemas(target, source map[string]interface{}) {
+	for key, value := range source {
+		switch key {
+		case "properties":
+			targetProperties, _ := target[key].(map[string]interface{})
+			merged := make(map[string]interface{}, len(targetProperties))
+			for name, property := range targetProperties {
+				merged[name] = property
+			}
+			if sourceProperties, ok := value.(map[string]interface{}); ok {
+				for name, property := range sourceProperties {
+					merged[name] = property
+				}
+			}
+			target[key] = merged
+
+		case "required":
+			targetRequired, _ := target[key].([]interface{})
+			merged := append([]interface{}{}, targetRequired...)
+			if sourceRequired, ok := value.([]interface{}); ok {
+				merged = append(merged, sourceRequired...)
+			}
+			target[key] = merged
+
+		default:
+			if _, exists := target[key]; !exists {
+				target[key] = value
+			}
+		}
+	}
+}
+
+// schemaDefinitions returns the reusable definitions of a root schema
+func schemaDefinitions(schema map[string]interface{}) map[string]interface{} {
+	defs := make(map[string]interface{})
+	for _, key := range []string{"definitions", "$defs"} {
+		if values, ok := schema[key].(map[string]interface{}); ok {
+			for name, value := range values {
+				defs["#/"+key+"/"+name] = value
+			}
+		}
+	}
+	defs["#"] = schema
+	return defs
+}
+
+// resolveSchemaRef resolves a local $ref such as #/$defs/Item
+func resolveSchemaRef(ref string, defs map[string]interface{}) map[string]interface{} {
+	if !strings.HasPrefix(ref, "#") {
+		return nil
+	}
+	resolved, _ := defs[ref].(map[string]interface{})
+	return resolved
+}
+
+// allStrings reports whether every value in the slice is a string
+func allStrings(values []interface{}) bool {
+	for _, value := range values {
+		if _, ok := value.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package gemini
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gemini-cli-go/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseSchema decodes a JSON schema literal
+func parseSchema(t *testing.T, schema string) map[string]interface{} {
+	t.Helper()
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(schema), &decoded))
+	return decoded
+}
+
+func TestConvertJSONSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{
+			name:   "ref is inlined with sibling keywords taking precedence",
+			schema: `{"type":"object","properties":{"item":{"$ref":"#/$defs/Item","description":"the item"}},"$defs":{"Item":{"type":"object","description":"an item","properties":{"name":{"type":"string"}}}}}`,
+			want:   `{"type":"object","properties":{"item":{"type":"object","description":"the item","properties":{"name":{"type":"string"}}}}}`,
+		},
+		{
+			name:   "definitions refs are resolved",
+			schema: `{"type":"array","items":{"$ref":"#/definitions/Tag"},"definitions":{"Tag":{"type":"string","enum":["a","b"]}}}`,
+			want:   `{"type":"array","items":{"type":"string","enum":["a","b"]}}`,
+		},
+		{
+			name:   "anyOf with a null branch becomes nullable",
+			schema: `{"anyOf":[{"type":"string"},{"type":"null"}]}`,
+			want:   `{"type":"string","nullable":true}`,
+		},
+		{
+			name:   "anyOf with several branches is kept",
+			schema: `{"anyOf":[{"type":"string"},{"type":"integer"},{"type":"null"}]}`,
+			want:   `{"anyOf":[{"type":"string"},{"type":"integer"}],"nullable":true}`,
+		},
+		{
+			name:   "type lists become nullable",
+			schema: `{"type":["integer","null"]}`,
+			want:   `{"type":"integer","nullable":true}`,
+		},
+		{
+			name:   "oneOf is treated as anyOf",
+			schema: `{"oneOf":[{"type":"string"},{"type":"number"}]}`,
+			want:   `{"anyOf":[{"type":"string"},{"type":"number"}]}`,
+		},
+		{
+			name:   "allOf is merged",
+			schema: `{"allOf":[{"type":"object","properties":{"a":{"type":"string"}},"required":["a"]},{"properties":{"b":{"type":"number"}},"required":["b"]}]}`,
+			want:   `{"type":"object","properties":{"a":{"type":"string"},"b":{"type":"number"}},"required":["a","b"]}`,
+		},
+		{
+			name:   "const becomes a single value enum",
+			schema: `{"type":"string","const":"fixed"}`,
+			want:   `{"type":"string","enum":["fixed"]}`,
+		},
+		{
+			name:   "unsupported keywords are dropped",
+			schema: `{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","additionalProperties":false,"properties":{"n":{"type":"number","exclusiveMinimum":0,"default":1}},"$defs":{}}`,
+			want:   `{"type":"object","properties":{"n":{"type":"number"}}}`,
+		},
+		{
+			name:   "unsupported string formats and non-string enums are dropped",
+			schema: `{"type":"object","properties":{"email":{"type":"string","format":"email"},"when":{"type":"string","format":"date-time"},"level":{"type":"integer","enum":[1,2]}}}`,
+			want:   `{"type":"object","properties":{"email":{"type":"string"},"when":{"type":"string","format":"date-time"},"level":{"type":"integer"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converted, err := json.Marshal(convertJSONSchema(parseSchema(t, tt.schema)))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(converted))
+		})
+	}
+}
+
+func TestConvertJSONSchemaRecursiveRef(t *testing.T) {
+	schema := parseSchema(t, `{"$ref":"#/$defs/Node","$defs":{"Node":{"type":"object","properties":{"child":{"$ref":"#/$defs/Node"}}}}}`)
+
+	// Recursive definitions are inlined up to the maximum depth, the innermost reference is dropped
+	depth := 0
+	for node := convertJSONSchema(schema); node != nil; depth++ {
+		assert.Equal(t, "object", node["type"])
+		properties, _ := node["properties"].(map[string]interface{})
+		node, _ = properties["child"].(map[string]interface{})
+	}
+	assert.Equal(t, maxSchemaRefDepth, depth)
+}
+
+func TestValidateResponseFormat(t *testing.T) {
+	strict := true
+	schema := `{"type":"object","properties":{"name":{"type":"string","minLength":1},"age":{"type":"integer","minimum":0},"tags":{"type":"array","items":{"$ref":"#/$defs/Tag"},"maxItems":2},"nickname":{"anyOf":[{"type":"string"},{"type":"null"}]}},"required":["name","age"],"additionalProperties":false,"$defs":{"Tag":{"type":"string","enum":["a","b"]}}}`
+
+	jsonSchema := func(strict *bool) *types.ResponseFormat {
+		return &types.ResponseFormat{
+			Type:       ResponseFormatJSONSchema,
+			JSONSchema: &types.ResponseFormatJSONSchema{Name: "person", Schema: parseSchema(t, schema), Strict: strict},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		format  *types.ResponseFormat
+		content string
+		errPath string
+	}{
+		{name: "no format", format: nil, content: "plain text"},
+		{name: "text format", format: &types.ResponseFormat{Type: ResponseFormatText}, content: "plain text"},
+		{name: "json object", format: &types.ResponseFormat{Type: ResponseFormatJSONObject}, content: `{"a":1}`},
+		{name: "json object with invalid JSON", format: &types.ResponseFormat{Type: ResponseFormatJSONObject}, content: `{"a":`, errPath: "$"},
+		{name: "json object with an array", format: &types.ResponseFormat{Type: ResponseFormatJSONObject}, content: `[1]`, errPath: "$"},
+		{name: "valid strict schema", format: jsonSchema(&strict), content: `{"name":"Ada","age":36,"tags":["a"],"nickname":null}`},
+		{name: "missing required property", format: jsonSchema(&strict), content: `{"name":"Ada"}`, errPath: "$"},
+		{name: "wrong type", format: jsonSchema(&strict), content: `{"name":"Ada","age":36.5}`, errPath: "$.age"},
+		{name: "below minimum", format: jsonSchema(&strict), content: `{"name":"Ada","age":-1}`, errPath: "$.age"},
+		{name: "too short", format: jsonSchema(&strict), content: `{"name":"","age":1}`, errPath: "$.name"},
+		{name: "enum through ref", format: jsonSchema(&strict), content: `{"name":"Ada","age":1,"tags":["c"]}`, errPath: "$.tags[0]"},
+		{name: "too many items", format: jsonSchema(&strict), content: `{"name":"Ada","age":1,"tags":["a","b","a"]}`, errPath: "$.tags"},
+		{name: "no anyOf branch matches", format: jsonSchema(&strict), content: `{"name":"Ada","age":1,"nickname":3}`, errPath: "$.nickname"},
+		{name: "additional property", format: jsonSchema(&strict), content: `{"name":"Ada","age":1,"extra":true}`, errPath: "$.extra"},
+		{name: "schemas are not enforced without strict", format: jsonSchema(nil), content: `{"name":"Ada"}`},
+		{name: "invalid JSON without strict", format: jsonSchema(nil), content: `not json`, errPath: "$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateResponseFormat(tt.format, tt.content)
+			if tt.errPath == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var validationErr *SchemaValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.errPath, validationErr.Path)
+		})
+	}
+}
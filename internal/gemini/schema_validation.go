@@ -0,0 +1,275 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gemini-cli-go/internal/types"
+)
+
+// Response format types
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// SchemaValidationError describes model output that does not match the requested response format
+type SchemaValidationError struct {
+	Path    string
+	Message string
+}
+
+// Error implements the error interface
+func (e *SchemaValidationError) Error() string {
+	return fmt.Sprintf("model output does not match the response format at %s: %s", e.Path, e.Message)
+}
+
+// RequiresResponseValidation reports whether output must be checked against the response format
+func RequiresResponseValidation(format *types.ResponseFormat) bool {
+	return format != nil && format.Type != "" && format.Type != ResponseFormatText
+}
+
+// ValidateResponseFormat checks that a completion matches the requested response format.
+// Schemas are only enforced when strict is set, mirroring OpenAI's structured outputs.
+func ValidateResponseFormat(format *types.ResponseFormat, content string) error {
+	if !RequiresResponseValidation(format) {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		return &SchemaValidationError{Path: "$", Message: "output is not valid JSON"}
+	}
+
+	switch format.Type {
+	case ResponseFormatJSONObject:
+		if _, ok := value.(map[string]interface{}); !ok {
+			return &SchemaValidationError{Path: "$", Message: "output is not a JSON object"}
+		}
+
+	case ResponseFormatJSONSchema:
+		if format.JSONSchema == nil || format.JSONSchema.Strict == nil || !*format.JSONSchema.Strict {
+			return nil
+		}
+		schema := format.JSONSchema.Schema
+		return validateSchemaValue(schema, schemaDefinitions(schema), value, "$", 0)
+	}
+
+	return nil
+}
+
+// validateSchemaValue validates a decoded JSON value against a JSON Schema node
+func validateSchemaValue(schema map[string]interface{}, defs map[string]interface{}, value interface{}, path string, depth int) error {
+	if schema == nil {
+		return nil
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		resolved := resolveSchemaRef(ref, defs)
+		if resolved == nil || depth >= maxSchemaRefDepth {
+			return nil
+		}
+		if err := validateSchemaValue(resolved, defs, value, path, depth+1); err != nil {
+			return err
+		}
+	}
+
+	if typeValue, ok := schema["type"]; ok && !matchesSchemaType(typeValue, value) {
+		return &SchemaValidationError{Path: path, Message: fmt.Sprintf("expected type %v", typeValue)}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsJSONValue(enum, value) {
+		return &SchemaValidationError{Path: path, Message: "value is not one of the allowed enum values"}
+	}
+
+	if constValue, ok := schema["const"]; ok && !jsonValuesEqual(constValue, value) {
+		return &SchemaValidationError{Path: path, Message: "value does not match const"}
+	}
+
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if variants, ok := schema[key].([]interface{}); ok {
+			matched := false
+			for _, variant := range variants {
+				if variantSchema, ok := variant.(map[string]interface{}); ok {
+					if validateSchemaValue(variantSchema, defs, value, path, depth) == nil {
+						matched = true
+						break
+					}
+				}
+			}
+			if !matched {
+				return &SchemaValidationError{Path: path, Message: fmt.Sprintf("value does not match any %s branch", key)}
+			}
+		}
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, item := range allOf {
+			if subSchema, ok := item.(map[string]interface{}); ok {
+				if err := validateSchemaValue(subSchema, defs, value, path, depth); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		return validateSchemaObject(schema, defs, typed, path, depth)
+	case []interface{}:
+		return validateSchemaArray(schema, defs, typed, path, depth)
+	case string:
+		length := float64(utf8.RuneCountInString(typed))
+		if minLength, ok := schema["minLength"].(float64); ok && length < minLength {
+			return &SchemaValidationError{Path: path, Message: fmt.Sprintf("string is shorter than %v", minLength)}
+		}
+		if maxLength, ok := schema["maxLength"].(float64); ok && length > maxLength {
+			return &SchemaValidationError{Path: path, Message: fmt.Sprintf("string is longer than %v", maxLength)}
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(typed) {
+				return &SchemaValidationError{Path: path, Message: fmt.Sprintf("string does not match pattern %q", pattern)}
+			}
+		}
+	case float64:
+		if minimum, ok := schema["minimum"].(float64); ok && typed < minimum {
+			return &SchemaValidationError{Path: path, Message: fmt.Sprintf("number is less than %v", minimum)}
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && typed > maximum {
+			return &SchemaValidationError{Path: path, Message: fmt.Sprintf("number is greater than %v", maximum)}
+		}
+	}
+
+	return nil
+}
+
+// validateSchemaObject validates the properties of a JSON object
+func validateSchemaObject(schema map[string]interface{}, defs map[string]interface{}, object map[string]interface{}, path string, depth int) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, exists := object[key]; !exists {
+					return &SchemaValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", key)}
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	// Iterate in a stable order so the reported error is deterministic
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		propertyPath := path + "." + key
+		if propertySchema, ok := properties[key].(map[string]interface{}); ok {
+			if err := validateSchemaValue(propertySchema, defs, object[key], propertyPath, depth); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return &SchemaValidationError{Path: propertyPath, Message: "additional property is not allowed"}
+			}
+		case map[string]interface{}:
+			if err := validateSchemaValue(additional, defs, object[key], propertyPath, depth); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateSchemaArray validates the items of a JSON array
+func validateSchemaArray(schema map[string]interface{}, defs map[string]interface{}, array []interface{}, path string, depth int) error {
+	if minItems, ok := schema["minItems"].(float64); ok && float64(len(array)) < minItems {
+		return &SchemaValidationError{Path: path, Message: fmt.Sprintf("array has fewer than %v items", minItems)}
+	}
+	if maxItems, ok := schema["maxItems"].(float64); ok && float64(len(array)) > maxItems {
+		return &SchemaValidationError{Path: path, Message: fmt.Sprintf("array has more than %v items", maxItems)}
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range array {
+			if err := validateSchemaValue(items, defs, item, fmt.Sprintf("%s[%d]", path, i), depth); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchesSchemaType reports whether a value matches a JSON Schema type or list of types
+func matchesSchemaType(typeValue interface{}, value interface{}) bool {
+	switch typed := typeValue.(type) {
+	case string:
+		return matchesSingleType(typed, value)
+	case []interface{}:
+		for _, t := range typed {
+			if typeName, ok := t.(string); ok && matchesSingleType(typeName, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// matchesSingleType reports whether a value matches a single JSON Schema type
+func matchesSingleType(typeName string, value interface{}) bool {
+	switch strings.ToLower(typeName) {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+// containsJSONValue reports whether values contains value
+func containsJSONValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if jsonValuesEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonValuesEqual compares two decoded JSON values
+func jsonValuesEqual(a, b interface{}) bool {
+	aBytes, errA := json.Marshal(a)
+	bBytes, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(aBytes) == string(bBytes)
+}
@@ -0,0 +1,105 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"gemini-cli-go/internal/config"
+)
+
+// newHTTPClient creates the upstream HTTP client. It has no overall timeout, since a stream may
+// run for minutes: requests are bounded by their context, and streams by a streamWatchdog.
+func newHTTPClient(cfg *config.Config) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   cfg.GetUpstreamConnectTimeout(),
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = cfg.GetUpstreamConnectTimeout()
+
+	return &http.Client{Transport: transport}
+}
+
+// withRequestDeadline bounds ctx by a timeout, leaving it unbounded when the timeout is not positive
+func withRequestDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// streamWatchdog cancels an upstream stream that does not deliver its first byte within the
+// first byte timeout, or that stays silent for longer than the idle timeout afterwards
+type streamWatchdog struct {
+	timer     *time.Timer
+	firstByte time.Duration
+	idle      time.Duration
+	started   atomic.Bool
+	expired   atomic.Bool
+}
+
+// newStreamWatchdog starts a watchdog calling cancel when a timeout passes, a non-positive timeout disables that limit
+func newStreamWatchdog(cancel context.CancelFunc, firstByte, idle time.Duration) *streamWatchdog {
+	w := &streamWatchdog{firstByte: firstByte, idle: idle}
+	w.timer = time.AfterFunc(time.Hour, func() {
+		w.expired.Store(true)
+		cancel()
+	})
+	w.arm(firstByte)
+	return w
+}
+
+// arm restarts the timer, or stops it when the timeout is disabled
+func (w *streamWatchdog) arm(timeout time.Duration) {
+	if timeout <= 0 {
+		w.timer.Stop()
+		return
+	}
+	w.timer.Reset(timeout)
+}
+
+// watch wraps a response body, so every read that returns data restarts the idle timeout
+func (w *streamWatchdog) watch(body io.ReadCloser) io.ReadCloser {
+	return &watchedBody{ReadCloser: body, watchdog: w}
+}
+
+// stop stops the watchdog once the stream is done, a nil watchdog is ignored
+func (w *streamWatchdog) stop() {
+	if w != nil {
+		w.timer.Stop()
+	}
+}
+
+// check replaces the cancellation error of an expired stream with a timeout error, a nil watchdog passes err through
+func (w *streamWatchdog) check(err error) error {
+	if w == nil || err == nil || !w.expired.Load() {
+		return err
+	}
+	if !w.started.Load() {
+		return &UpstreamError{Message: fmt.Sprintf("no response from upstream within %v", w.firstByte), Err: context.DeadlineExceeded}
+	}
+	return &UpstreamError{Message: fmt.Sprintf("upstream stream idle for more than %v", w.idle), Err: context.DeadlineExceeded}
+}
+
+// watchedBody is a response body whose reads feed a streamWatchdog
+type watchedBody struct {
+	io.ReadCloser
+	watchdog *streamWatchdog
+}
+
+// Read reads from the body, restarting the idle timeout when data arrives
+func (b *watchedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.watchdog.expired.Load() {
+		b.watchdog.started.Store(true)
+		b.watchdog.arm(b.watchdog.idle)
+	}
+	return n, err
+}
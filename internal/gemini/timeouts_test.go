@@ -0,0 +1,145 @@
+package gemini
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gemini-cli-go/internal/types"
+)
+
+// waitDone fails the test when ctx is not done within a second
+func waitDone(t *testing.T, ctx context.Context) {
+	t.Helper()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context was not cancelled")
+	}
+}
+
+func TestStreamWatchdogFirstByteTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	watchdog := newStreamWatchdog(cancel, 10*time.Millisecond, time.Hour)
+	defer watchdog.stop()
+
+	waitDone(t, ctx)
+	var upstreamErr *UpstreamError
+	require.ErrorAs(t, watchdog.check(ctx.Err()), &upstreamErr)
+	assert.Equal(t, "no response from upstream within 10ms", upstreamErr.Message)
+	assert.True(t, upstreamErr.IsTimeout())
+}
+
+func TestStreamWatchdogIdleTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	watchdog := newStreamWatchdog(cancel, time.Hour, 20*time.Millisecond)
+	defer watchdog.stop()
+
+	reader, writer := io.Pipe()
+	body := watchdog.watch(reader)
+	go func() {
+		// Data arriving within the idle timeout keeps the stream alive
+		for i := 0; i < 5; i++ {
+			writer.Write([]byte("data"))
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	buf := make([]byte, 4)
+	for i := 0; i < 5; i++ {
+		_, err := body.Read(buf)
+		require.NoError(t, err)
+	}
+	assert.NoError(t, ctx.Err())
+
+	waitDone(t, ctx)
+	var upstreamErr *UpstreamError
+	require.ErrorAs(t, watchdog.check(ctx.Err()), &upstreamErr)
+	assert.Equal(t, "upstream stream idle for more than 20ms", upstreamErr.Message)
+}
+
+func TestStreamWatchdogCheck(t *testing.T) {
+	var nilWatchdog *streamWatchdog
+	assert.Equal(t, io.EOF, nilWatchdog.check(io.EOF))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watchdog := newStreamWatchdog(cancel, 0, 0)
+	watchdog.stop()
+
+	// Errors of a stream that did not time out pass through
+	assert.Equal(t, io.EOF, watchdog.check(io.EOF))
+	assert.NoError(t, watchdog.check(nil))
+	assert.NoError(t, ctx.Err())
+}
+
+func TestRelayChunksTimeout(t *testing.T) {
+	chunkChan := relayChunks(context.Background(), 10*time.Millisecond, func(ctx context.Context, chunkChan chan<- types.StreamChunk) {
+		chunkChan <- types.StreamChunk{Type: types.StreamChunkTypeText, Data: "Hi"}
+		<-ctx.Done()
+		chunkChan <- types.StreamChunk{Type: types.StreamChunkTypeError, Data: ctx.Err()}
+	})
+
+	var chunks []types.StreamChunk
+	for chunk := range chunkChan {
+		chunks = append(chunks, chunk)
+	}
+	require.Len(t, chunks, 2)
+	assert.Equal(t, types.StreamChunkTypeError, chunks[1].Type)
+	var upstreamErr *UpstreamError
+	require.ErrorAs(t, chunks[1].Data.(error), &upstreamErr)
+	assert.Equal(t, "request exceeded the maximum duration of 10ms", upstreamErr.Message)
+}
+
+func TestRelayChunksClientGone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	produced := make(chan struct{})
+	chunkChan := relayChunks(ctx, 0, func(ctx context.Context, chunkChan chan<- types.StreamChunk) {
+		defer close(produced)
+		<-ctx.Done()
+		// Chunks sent after the client went away are discarded instead of blocking
+		chunkChan <- types.StreamChunk{Type: types.StreamChunkTypeText, Data: "late"}
+	})
+
+	cancel()
+	for range chunkChan {
+	}
+	select {
+	case <-produced:
+	case <-time.After(time.Second):
+		t.Fatal("producer blocked after the client went away")
+	}
+}
+
+func TestStreamContentUnaryUsesRequestTimeout(t *testing.T) {
+	env := retryEnv
+	env.RequestTimeout = 1
+	release := make(chan struct{})
+	client, _ := newTestClient(t, env, func(w http.ResponseWriter, model string, attempt int) {
+		// Answer with headers only, then stay silent
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-release
+	})
+	t.Cleanup(func() { close(release) })
+
+	start := time.Now()
+	chunkChan, err := client.StreamContent(context.Background(), "gemini-2.5-flash", "", userMessage, &StreamOptions{Unary: true})
+	require.NoError(t, err)
+
+	var lastErr error
+	for chunk := range chunkChan {
+		if chunk.Type == types.StreamChunkTypeError {
+			lastErr = chunk.Data.(error)
+		}
+	}
+	var upstreamErr *UpstreamError
+	require.ErrorAs(t, lastErr, &upstreamErr)
+	assert.Equal(t, "request exceeded the maximum duration of 1s", upstreamErr.Message)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
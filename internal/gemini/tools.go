@@ -0,0 +1,244 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gemini-cli-go/internal/types"
+
+	"github.com/google/uuid"
+)
+
+// Gemini function calling modes
+const (
+	FunctionCallingModeAuto = "AUTO"
+	FunctionCallingModeAny  = "ANY"
+	FunctionCallingModeNone = "NONE"
+)
+
+// Pseudo-tool types enabling Gemini built-in tools, in tools or as model suffixes like gemini-2.5-pro:search
+const (
+	ToolTypeGoogleSearch  = "google_search"
+	ToolTypeURLContext    = "url_context"
+	ToolTypeCodeExecution = "code_execution"
+)
+
+// modelSuffixTools maps model suffixes to the pseudo-tool they enable
+var modelSuffixTools = map[string]string{
+	"search":         ToolTypeGoogleSearch,
+	"url_context":    ToolTypeURLContext,
+	"code_execution": ToolTypeCodeExecution,
+}
+
+// applyModelSuffixes strips suffixes like :search from a model ID, returning the model and options
+// with the matching pseudo-tools added. The options of the caller are left untouched.
+func applyModelSuffixes(modelID string, options *StreamOptions) (string, *StreamOptions, error) {
+	model, suffixes, found := strings.Cut(modelID, ":")
+	if !found {
+		return modelID, options, nil
+	}
+
+	withTools := StreamOptions{}
+	if options != nil {
+		withTools = *options
+	}
+	withTools.Tools = append([]types.Tool(nil), withTools.Tools...)
+
+	for _, suffix := range strings.Split(suffixes, ":") {
+		toolType, ok := modelSuffixTools[suffix]
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported model suffix: %s", suffix)
+		}
+		withTools.Tools = append(withTools.Tools, types.Tool{Type: toolType})
+	}
+	return model, &withTools, nil
+}
+
+// convertToolsToGemini converts OpenAI tool definitions to Gemini function declarations,
+// and pseudo-tools to the built-in tools they enable
+func convertToolsToGemini(tools []types.Tool) ([]GeminiTool, error) {
+	if len(tools) == 0 {
+		return nil, nil
+	}
+
+	var declarations []GeminiFunctionDeclaration
+	var builtins []GeminiTool
+	enabled := make(map[string]bool)
+	for _, tool := range tools {
+		switch tool.Type {
+		case ToolTypeGoogleSearch, ToolTypeURLContext, ToolTypeCodeExecution:
+			// The same built-in tool may be enabled by a suffix and in tools
+			if !enabled[tool.Type] {
+				enabled[tool.Type] = true
+				builtins = append(builtins, builtinTool(tool.Type))
+			}
+			continue
+		}
+
+		if tool.Type != "function" || tool.Function == nil {
+			return nil, fmt.Errorf("unsupported tool type: %s", tool.Type)
+		}
+		if tool.Function.Name == "" {
+			return nil, fmt.Errorf("tool function name is required")
+		}
+
+		declarations = append(declarations, GeminiFunctionDeclaration{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			Parameters:  convertJSONSchema(tool.Function.Parameters),
+		})
+	}
+
+	if len(declarations) == 0 {
+		return builtins, nil
+	}
+	return append([]GeminiTool{{FunctionDeclarations: declarations}}, builtins...), nil
+}
+
+// builtinTool returns the Gemini built-in tool enabled by a pseudo-tool type
+func builtinTool(toolType string) GeminiTool {
+	switch toolType {
+	case ToolTypeURLContext:
+		return GeminiTool{URLContext: &struct{}{}}
+	case ToolTypeCodeExecution:
+		return GeminiTool{CodeExecution: &struct{}{}}
+	default:
+		return GeminiTool{GoogleSearch: &struct{}{}}
+	}
+}
+
+// convertToolChoiceToGemini converts an OpenAI tool_choice value to a Gemini tool config
+func convertToolChoiceToGemini(toolChoice interface{}) (*GeminiToolConfig, error) {
+	switch choice := toolChoice.(type) {
+	case nil:
+		return nil, nil
+
+	case string:
+		switch choice {
+		case "auto":
+			return &GeminiToolConfig{FunctionCallingConfig: GeminiFunctionCallingConfig{Mode: FunctionCallingModeAuto}}, nil
+		case "none":
+			return &GeminiToolConfig{FunctionCallingConfig: GeminiFunctionCallingConfig{Mode: FunctionCallingModeNone}}, nil
+		case "required":
+			return &GeminiToolConfig{FunctionCallingConfig: GeminiFunctionCallingConfig{Mode: FunctionCallingModeAny}}, nil
+		}
+
+	case map[string]interface{}:
+		if function, ok := choice["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok && name != "" {
+				return &GeminiToolConfig{FunctionCallingConfig: GeminiFunctionCallingConfig{
+					Mode:                 FunctionCallingModeAny,
+					AllowedFunctionNames: []string{name},
+				}}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("invalid tool_choice: %v", toolChoice)
+}
+
+// convertToolCallToGeminiPart converts an assistant tool call to a Gemini functionCall part
+func convertToolCallToGeminiPart(toolCall types.ToolCall) (*types.GeminiPart, error) {
+	args := map[string]interface{}{}
+	if strings.TrimSpace(toolCall.Function.Arguments) != "" {
+		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments for tool call %s: %w", toolCall.ID, err)
+		}
+	}
+
+	return &types.GeminiPart{
+		FunctionCall: &types.GeminiFunctionCall{
+			Name: toolCall.Function.Name,
+			Args: args,
+		},
+	}, nil
+}
+
+// convertToolResultToGeminiPart converts a tool role message to a Gemini functionResponse part
+func convertToolResultToGeminiPart(msg types.ChatMessage, toolNames map[string]string) (*types.GeminiPart, error) {
+	name := toolNames[msg.ToolCallID]
+	if name == "" {
+		name = msg.Name
+	}
+	if name == "" {
+		return nil, fmt.Errorf("tool message references unknown tool_call_id: %s", msg.ToolCallID)
+	}
+
+	// Gemini expects the function response to be a JSON object
+	text := messageText(msg.Content)
+	var response map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &response); err != nil || response == nil {
+		var value interface{}
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			value = text
+		}
+		response = map[string]interface{}{"content": value}
+	}
+
+	return &types.GeminiPart{
+		FunctionResponse: &types.GeminiFunctionResponse{
+			Name:     name,
+			Response: response,
+		},
+	}, nil
+}
+
+// convertFunctionCallToToolCall converts a Gemini functionCall part to an OpenAI tool call
+func convertFunctionCallToToolCall(functionCall *types.GeminiFunctionCall) (types.ToolCall, error) {
+	args := functionCall.Args
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+
+	arguments, err := json.Marshal(args)
+	if err != nil {
+		return types.ToolCall{}, fmt.Errorf("failed to marshal function call arguments: %w", err)
+	}
+
+	return types.ToolCall{
+		ID:   "call_" + strings.ReplaceAll(uuid.New().String(), "-", ""),
+		Type: "function",
+		Function: types.ToolCallFunction{
+			Name:      functionCall.Name,
+			Arguments: string(arguments),
+		},
+	}, nil
+}
+
+// isFunctionResponseTurn reports whether a Gemini message only carries function responses
+func isFunctionResponseTurn(msg types.GeminiFormattedMessage) bool {
+	if msg.Role != "user" || len(msg.Parts) == 0 {
+		return false
+	}
+	for _, part := range msg.Parts {
+		if part.FunctionResponse == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// messageText extracts the text of a message content, which may be a string or an array of parts
+func messageText(content interface{}) string {
+	switch value := content.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case []interface{}:
+		var textParts []string
+		for _, item := range value {
+			if itemMap, ok := item.(map[string]interface{}); ok {
+				if itemType, ok := itemMap["type"].(string); ok && itemType == "text" {
+					if text, ok := itemMap["text"].(string); ok {
+						textParts = append(textParts, text)
+					}
+				}
+			}
+		}
+		return strings.Join(textParts, "\n")
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
@@ -18,6 +18,9 @@ type StreamOptions struct {
 	
 	// ThinkingBudget controls the token budget for thinking
 	ThinkingBudget *int `json:"thinking_budget,omitempty"`
+
+	// ReasoningEffort picks a thinking budget for the model when ThinkingBudget is not set
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
 	
 	// EnableRealThinking enables real thinking from Gemini
 	EnableRealThinking bool `json:"enable_real_thinking"`
@@ -30,19 +33,147 @@ type StreamOptions struct {
 	
 	// IncludeReasoning includes reasoning in the response
 	IncludeReasoning bool `json:"include_reasoning"`
+
+	// Tools lists the OpenAI tool definitions available to the model
+	Tools []types.Tool `json:"tools,omitempty"`
+
+	// ToolChoice controls which (if any) tool is called by the model
+	ToolChoice interface{} `json:"tool_choice,omitempty"`
+
+	// ParallelToolCalls allows more than one tool call per turn when not false
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+
+	// ResponseFormat requests JSON output, optionally constrained by a schema
+	ResponseFormat *types.ResponseFormat `json:"response_format,omitempty"`
+
+	// SafetySettings overrides the configured safety settings per harm category
+	SafetySettings []types.GeminiSafetySetting `json:"safety_settings,omitempty"`
+
+	// StopSequences stops generation when one of the sequences is produced
+	StopSequences []string `json:"stop_sequences,omitempty"`
+
+	// TopK limits sampling to the k most likely tokens
+	TopK *int `json:"top_k,omitempty"`
+
+	// Seed makes sampling deterministic on a best-effort basis
+	Seed *int `json:"seed,omitempty"`
+
+	// PresencePenalty penalizes tokens that already appeared in the response
+	PresencePenalty *float64 `json:"presence_penalty,omitempty"`
+
+	// FrequencyPenalty penalizes tokens by how often they appeared in the response
+	FrequencyPenalty *float64 `json:"frequency_penalty,omitempty"`
+
+	// CandidateCount is the number of response candidates to generate
+	CandidateCount *int `json:"candidate_count,omitempty"`
+
+	// CodeExecutionFormat returns code execution parts as structured chunks when set to
+	// structured, and as markdown text otherwise
+	CodeExecutionFormat string `json:"code_execution_format,omitempty"`
+
+	// NativeMethod forwards the request untranslated to this Code Assist method and
+	// returns the unwrapped responses as raw chunks
+	NativeMethod string `json:"native_method,omitempty"`
+
+	// Unary marks a request whose response is sent as a whole, it is bounded by the
+	// request timeout instead of the maximum stream duration
+	Unary bool `json:"unary,omitempty"`
 }
 
 // CompletionResult represents the result of a completion request
 type CompletionResult struct {
-	Content string           `json:"content"`
-	Usage   *types.UsageData `json:"usage,omitempty"`
+	Model      string            `json:"model"`
+	Candidates []CandidateResult `json:"candidates"`
+	Usage      *types.UsageData  `json:"usage,omitempty"`
+}
+
+// CandidateResult represents one response candidate, there is always at least one
+type CandidateResult struct {
+	Index         int                        `json:"index"`
+	Content       string                     `json:"content"`
+	Reasoning     string                     `json:"reasoning,omitempty"`
+	ToolCalls     []types.ToolCall           `json:"tool_calls,omitempty"`
+	Annotations   []types.Annotation         `json:"annotations,omitempty"`
+	CodeExecution []types.CodeExecution      `json:"code_execution,omitempty"`
+	FinishReason  string                     `json:"finish_reason"`
+	SafetyRatings []types.GeminiSafetyRating `json:"safety_ratings,omitempty"`
 }
 
 // StreamingContext holds context for streaming operations
 type StreamingContext struct {
+	HasSentChunks bool
+	candidates    map[int]*CandidateContext
+}
+
+// CandidateContext holds the streaming state of a single response candidate
+type CandidateContext struct {
 	HasStartedThinking bool
 	HasClosedThinking  bool
 	NeedsThinkingClose bool
+	ToolCallCount      int
+	SafetyRatings      []types.GeminiSafetyRating
+
+	// text is the answer text so far, which grounding offsets refer to, cited the citations already sent
+	text  strings.Builder
+	cited map[types.URLCitation]bool
+
+	// rendered lists the code execution blocks rendered into text
+	rendered []renderedBlock
+}
+
+// renderedBlock is a block of text that Gemini did not send as text, inserted at a byte offset of
+// Gemini's text
+type renderedBlock struct {
+	offset int
+	length int
+}
+
+// renderedLength returns the number of bytes of text that were rendered
+func (cc *CandidateContext) renderedLength() int {
+	length := 0
+	for _, block := range cc.rendered {
+		length += block.length
+	}
+	return length
+}
+
+// Candidate returns the state of the candidate with the given index
+func (sc *StreamingContext) Candidate(index int) *CandidateContext {
+	if sc.candidates == nil {
+		sc.candidates = make(map[int]*CandidateContext)
+	}
+	candidate, ok := sc.candidates[index]
+	if !ok {
+		candidate = &CandidateContext{}
+		sc.candidates[index] = candidate
+	}
+	return candidate
+}
+
+// GeminiTool represents a tool entry in a Gemini request
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations,omitempty"`
+	GoogleSearch         *struct{}                   `json:"googleSearch,omitempty"`
+	URLContext           *struct{}                   `json:"urlContext,omitempty"`
+	CodeExecution        *struct{}                   `json:"codeExecution,omitempty"`
+}
+
+// GeminiFunctionDeclaration represents a function declaration in a Gemini request
+type GeminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// GeminiToolConfig represents the tool configuration in a Gemini request
+type GeminiToolConfig struct {
+	FunctionCallingConfig GeminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+// GeminiFunctionCallingConfig controls how Gemini calls the declared functions
+type GeminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"`
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
 }
 
 // GenerationConfig represents Gemini generation configuration
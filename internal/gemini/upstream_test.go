@@ -0,0 +1,93 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"gemini-cli-go/internal/auth"
+	"gemini-cli-go/internal/config"
+	"gemini-cli-go/internal/types"
+)
+
+// testCredentials is a credential set with a valid token and project, so no OAuth or discovery call is made
+const testCredentials = `{"access_token":"test","refresh_token":"test","token_type":"Bearer","expiry_date":9999999999999,"project_id":"test-project"}`
+
+// fakeUpstream stands in for the Code Assist API, recording the model of every request
+type fakeUpstream struct {
+	mu     sync.Mutex
+	models []string
+}
+
+// requests returns the models requested so far, in order
+func (u *fakeUpstream) requests() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.models...)
+}
+
+// newTestClient creates a client whose upstream requests are answered by respond, which receives
+// the requested model and the number of requests made before
+func newTestClient(t *testing.T, env types.Environment, respond func(w http.ResponseWriter, model string, attempt int)) (*Client, *fakeUpstream) {
+	t.Helper()
+
+	upstream := &fakeUpstream{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		upstream.mu.Lock()
+		attempt := len(upstream.models)
+		upstream.models = append(upstream.models, body.Model)
+		upstream.mu.Unlock()
+
+		respond(w, body.Model, attempt)
+	}))
+	t.Cleanup(server.Close)
+
+	if env.GCPServiceAccount == "" {
+		env.GCPServiceAccount = testCredentials
+	}
+	cfg := &config.Config{Environment: env}
+	client := NewClient(cfg, auth.NewAuthManager(cfg))
+
+	target, _ := url.Parse(server.URL)
+	client.httpClient = &http.Client{Transport: redirectTransport{target: target}}
+	return client, upstream
+}
+
+// redirectTransport sends every request to the test server
+type redirectTransport struct {
+	target *url.URL
+}
+
+// RoundTrip implements http.RoundTripper
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// writeSSEText answers with a single SSE event holding text
+func writeSSEText(w http.ResponseWriter, text string) {
+	part, _ := json.Marshal(map[string]string{"text": text})
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprintf(w, "data: {\"response\":{\"candidates\":[{\"content\":{\"parts\":[%s]},\"finishReason\":\"STOP\"}]}}\n\n", part)
+}
+
+// writeGoogleError answers with a Google API error body
+func writeGoogleError(w http.ResponseWriter, statusCode int, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	fmt.Fprintf(w, `{"error":{"code":%d,"message":"%s","status":"%s"}}`, statusCode, http.StatusText(statusCode), status)
+}
+
+// userMessage is a single user turn
+var userMessage = []types.ChatMessage{{Role: "user", Content: "Hello"}}
@@ -0,0 +1,585 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"gemini-cli-go/internal/config"
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/gemini"
+	"gemini-cli-go/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AnthropicHandler handles Anthropic Messages API requests
+type AnthropicHandler struct {
+	config       *config.Config
+	geminiClient *gemini.Client
+}
+
+// NewAnthropicHandler creates a new Anthropic handler
+func NewAnthropicHandler(config *config.Config, geminiClient *gemini.Client) *AnthropicHandler {
+	return &AnthropicHandler{
+		config:       config,
+		geminiClient: geminiClient,
+	}
+}
+
+// Messages handles POST /v1/messages
+func (h *AnthropicHandler) Messages(c *gin.Context) {
+	var req types.AnthropicMessagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeAnthropicError(c, http.StatusBadRequest, anthropicErrorTypeInvalidRequest, err.Error())
+		return
+	}
+
+	if !h.validateModel(c, req.Model) {
+		return
+	}
+
+	if req.MaxTokens <= 0 {
+		writeAnthropicError(c, http.StatusBadRequest, anthropicErrorTypeInvalidRequest, "max_tokens: must be greater than 0")
+		return
+	}
+
+	systemPrompt, messages, options, err := h.convertRequest(req)
+	if err != nil {
+		writeAnthropicError(c, http.StatusBadRequest, anthropicErrorTypeInvalidRequest, err.Error())
+		return
+	}
+
+	options.Unary = !req.Stream
+	chunkChan, err := h.geminiClient.StreamContent(c.Request.Context(), req.Model, systemPrompt, messages, options)
+	if err != nil {
+		writeAnthropicClientError(c, err)
+		return
+	}
+
+	builder := newAnthropicMessageBuilder(req.Model)
+	if req.Stream {
+		builder.emit = func(event string, data interface{}) {
+			if !c.Writer.Written() {
+				c.Writer.Header().Set("Content-Type", constants.ContentTypeSSE)
+				c.Writer.Header().Set("Cache-Control", "no-cache")
+				c.Writer.Header().Set("Connection", "keep-alive")
+			}
+			c.SSEvent(event, data)
+			c.Writer.Flush()
+		}
+		setStreamDeadline(c, h.config.GetStreamMaxDuration())
+		chunkChan = withHeartbeats(c.Request.Context(), chunkChan, h.config.GetSSEHeartbeatInterval())
+	}
+
+	for chunk := range chunkChan {
+		if chunk.Type == types.StreamChunkTypeHeartbeat {
+			writeSSEHeartbeat(c)
+			continue
+		}
+
+		if chunk.Type == types.StreamChunkTypeError {
+			err, ok := chunk.Data.(error)
+			if !ok {
+				err = fmt.Errorf("%v", chunk.Data)
+			}
+			log.Printf("Messages request failed: %v", err)
+
+			// Errors before the first event still get a proper HTTP status
+			if req.Stream && c.Writer.Written() {
+				apiErr := classifyError(err)
+				c.SSEvent("error", newAnthropicErrorResponse(anthropicErrorType(apiErr), apiErr.message))
+				c.Writer.Flush()
+			} else {
+				writeAnthropicClientError(c, err)
+			}
+			return
+		}
+
+		if chunk.Type == types.StreamChunkTypeModel {
+			if model, ok := chunk.Data.(string); ok && !c.Writer.Written() {
+				c.Header(constants.HeaderGeminiFallback, model)
+			}
+		}
+
+		builder.add(chunk)
+	}
+
+	builder.finish()
+	if !req.Stream {
+		c.JSON(http.StatusOK, builder.response())
+	}
+}
+
+// CountTokens handles POST /v1/messages/count_tokens
+func (h *AnthropicHandler) CountTokens(c *gin.Context) {
+	var req types.AnthropicMessagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeAnthropicError(c, http.StatusBadRequest, anthropicErrorTypeInvalidRequest, err.Error())
+		return
+	}
+
+	if !h.validateModel(c, req.Model) {
+		return
+	}
+
+	systemPrompt, messages, options, err := h.convertRequest(req)
+	if err != nil {
+		writeAnthropicError(c, http.StatusBadRequest, anthropicErrorTypeInvalidRequest, err.Error())
+		return
+	}
+
+	inputTokens, err := h.geminiClient.CountTokens(c.Request.Context(), req.Model, systemPrompt, messages, options)
+	if err != nil {
+		writeAnthropicClientError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, types.AnthropicCountTokensResponse{InputTokens: inputTokens})
+}
+
+// validateModel writes a not_found_error like Anthropic's and returns false if the model is unknown
+func (h *AnthropicHandler) validateModel(c *gin.Context, model string) bool {
+	if apiErr := validateModel(model); apiErr != nil {
+		writeAnthropicError(c, http.StatusNotFound, anthropicErrorTypeNotFound, apiErr.message)
+		return false
+	}
+	return true
+}
+
+// convertRequest converts an Anthropic request to the system prompt, messages and options used by the Gemini client
+func (h *AnthropicHandler) convertRequest(req types.AnthropicMessagesRequest) (string, []types.ChatMessage, *gemini.StreamOptions, error) {
+	var messages []types.ChatMessage
+	for i, msg := range req.Messages {
+		converted, err := convertAnthropicMessage(msg)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("messages.%d: %w", i, err)
+		}
+		messages = append(messages, converted...)
+	}
+
+	tools, err := convertAnthropicTools(req.Tools)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	thinkingEnabled := req.Thinking != nil && req.Thinking.Type == "enabled"
+
+	options := &gemini.StreamOptions{
+		Temperature:        req.Temperature,
+		TopP:               req.TopP,
+		StopSequences:      req.StopSequences,
+		EnableRealThinking: thinkingEnabled,
+		EnableFakeThinking: thinkingEnabled && h.config.IsFakeThinkingEnabled(),
+		Tools:              tools,
+	}
+
+	if req.MaxTokens > 0 {
+		options.MaxTokens = &req.MaxTokens
+	}
+
+	if thinkingEnabled && req.Thinking.BudgetTokens > 0 {
+		options.ThinkingBudget = &req.Thinking.BudgetTokens
+	}
+
+	if req.ToolChoice != nil && len(tools) > 0 {
+		toolChoice, err := convertAnthropicToolChoice(req.ToolChoice)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		options.ToolChoice = toolChoice
+
+		if req.ToolChoice.DisableParallelToolUse {
+			parallel := false
+			options.ParallelToolCalls = &parallel
+		}
+	}
+
+	return anthropicContentText(req.System), messages, options, nil
+}
+
+// convertAnthropicMessage converts an Anthropic message to chat messages, splitting tool results into tool messages
+func convertAnthropicMessage(msg types.AnthropicMessage) ([]types.ChatMessage, error) {
+	if msg.Role != "user" && msg.Role != "assistant" {
+		return nil, fmt.Errorf("invalid role: %s", msg.Role)
+	}
+
+	var messages []types.ChatMessage
+	var parts []interface{}
+	var toolCalls []types.ToolCall
+
+	for _, block := range msg.Content {
+		switch block.Type {
+		case "text":
+			parts = append(parts, map[string]interface{}{"type": "text", "text": block.Text})
+
+		case "image":
+			part, err := anthropicImagePart(block.Source)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+
+		case "tool_use":
+			input := block.Input
+			if input == nil {
+				input = map[string]interface{}{}
+			}
+			arguments, err := json.Marshal(input)
+			if err != nil {
+				return nil, fmt.Errorf("invalid input for tool_use %s: %w", block.ID, err)
+			}
+			toolCalls = append(toolCalls, types.ToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: types.ToolCallFunction{
+					Name:      block.Name,
+					Arguments: string(arguments),
+				},
+			})
+
+		case "tool_result":
+			result, err := anthropicToolResult(block)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, result)
+
+			// Gemini function responses only carry JSON, so images are sent alongside them
+			for _, item := range block.Content {
+				if item.Type == "image" {
+					part, err := anthropicImagePart(item.Source)
+					if err != nil {
+						return nil, err
+					}
+					parts = append(parts, part)
+				}
+			}
+
+		case "thinking", "redacted_thinking":
+			// Gemini thoughts cannot be replayed, so earlier thinking is dropped from the history
+
+		default:
+			return nil, fmt.Errorf("unsupported content block type: %s", block.Type)
+		}
+	}
+
+	if len(parts) > 0 || len(toolCalls) > 0 {
+		message := types.ChatMessage{
+			Role:      msg.Role,
+			ToolCalls: toolCalls,
+		}
+		if len(parts) > 0 {
+			message.Content = parts
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// anthropicImagePart converts an Anthropic image source to an OpenAI image_url content part
+func anthropicImagePart(source *types.AnthropicImageSource) (map[string]interface{}, error) {
+	if source == nil {
+		return nil, fmt.Errorf("image block requires a source")
+	}
+
+	var url string
+	switch source.Type {
+	case "base64":
+		url = fmt.Sprintf("data:%s;base64,%s", source.MediaType, source.Data)
+	case "url":
+		url = source.URL
+	default:
+		return nil, fmt.Errorf("unsupported image source type: %s", source.Type)
+	}
+
+	return map[string]interface{}{
+		"type":      "image_url",
+		"image_url": map[string]interface{}{"url": url},
+	}, nil
+}
+
+// anthropicToolResult converts a tool_result block to a tool message
+func anthropicToolResult(block types.AnthropicContentBlock) (types.ChatMessage, error) {
+	content := anthropicContentText(block.Content)
+	if block.IsError {
+		errorContent, err := json.Marshal(map[string]interface{}{"error": content})
+		if err != nil {
+			return types.ChatMessage{}, err
+		}
+		content = string(errorContent)
+	}
+
+	return types.ChatMessage{
+		Role:       "tool",
+		ToolCallID: block.ToolUseID,
+		Content:    content,
+	}, nil
+}
+
+// anthropicContentText joins the text blocks of an Anthropic content list
+func anthropicContentText(content types.AnthropicContent) string {
+	var texts []string
+	for _, block := range content {
+		if block.Type == "text" && block.Text != "" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// convertAnthropicTools converts Anthropic client tools to OpenAI tool definitions
+func convertAnthropicTools(tools []types.AnthropicTool) ([]types.Tool, error) {
+	var converted []types.Tool
+	for _, tool := range tools {
+		if tool.Type != "" && tool.Type != "custom" {
+			return nil, fmt.Errorf("unsupported tool type: %s", tool.Type)
+		}
+		converted = append(converted, types.Tool{
+			Type: "function",
+			Function: &types.ToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+	return converted, nil
+}
+
+// convertAnthropicToolChoice converts an Anthropic tool_choice to an OpenAI tool_choice value
+func convertAnthropicToolChoice(choice *types.AnthropicToolChoice) (interface{}, error) {
+	switch choice.Type {
+	case "auto":
+		return "auto", nil
+	case "any":
+		return "required", nil
+	case "none":
+		return "none", nil
+	case "tool":
+		return map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": choice.Name},
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid tool_choice type: %s", choice.Type)
+}
+
+// anthropicStopReason maps an OpenAI finish reason to an Anthropic stop reason
+func anthropicStopReason(finishReason string) string {
+	switch finishReason {
+	case constants.FinishReasonLength:
+		return constants.StopReasonMaxTokens
+	case constants.FinishReasonToolCalls:
+		return constants.StopReasonToolUse
+	case constants.FinishReasonContentFilter:
+		return constants.StopReasonRefusal
+	}
+	return constants.StopReasonEndTurn
+}
+
+// anthropicMessageBuilder assembles Anthropic content blocks from Gemini stream chunks,
+// emitting the matching SSE events when streaming
+type anthropicMessageBuilder struct {
+	id         string
+	model      string
+	blocks     []types.AnthropicContentBlock
+	blockOpen  bool
+	stopReason string
+	usage      types.UsageData
+	started    bool
+	emit       func(event string, data interface{})
+}
+
+// newAnthropicMessageBuilder creates a builder for a message answered by model
+func newAnthropicMessageBuilder(model string) *anthropicMessageBuilder {
+	return &anthropicMessageBuilder{
+		id:     constants.AnthropicMessageIDPrefix + strings.ReplaceAll(uuid.New().String(), "-", ""),
+		model:  model,
+		blocks: []types.AnthropicContentBlock{},
+	}
+}
+
+// add applies a stream chunk to the message
+func (b *anthropicMessageBuilder) add(chunk types.StreamChunk) {
+	switch chunk.Type {
+	case types.StreamChunkTypeModel:
+		if model, ok := chunk.Data.(string); ok {
+			b.model = model
+		}
+	case types.StreamChunkTypeText:
+		if text, ok := chunk.Data.(string); ok {
+			b.appendText("text", text)
+		}
+	case types.StreamChunkTypeRealThinking:
+		if thinking, ok := chunk.Data.(string); ok {
+			b.appendText("thinking", thinking)
+		}
+	case types.StreamChunkTypeReasoning:
+		if reasoning, ok := chunk.Data.(types.ReasoningData); ok {
+			b.appendText("thinking", reasoning.Reasoning)
+		}
+	case types.StreamChunkTypeToolCall:
+		if toolCall, ok := chunk.Data.(types.ToolCall); ok {
+			b.addToolUse(toolCall)
+		}
+	case types.StreamChunkTypeFinishReason:
+		if finish, ok := chunk.Data.(types.FinishData); ok {
+			b.stopReason = anthropicStopReason(finish.FinishReason)
+		}
+	case types.StreamChunkTypeUsage:
+		if usage, ok := chunk.Data.(types.UsageData); ok {
+			b.usage = usage
+		}
+	}
+}
+
+// appendText appends text to the open text or thinking block, starting a new block when the type changes
+func (b *anthropicMessageBuilder) appendText(blockType string, text string) {
+	if text == "" {
+		return
+	}
+
+	last := len(b.blocks) - 1
+	if !b.blockOpen || b.blocks[last].Type != blockType {
+		b.closeBlock()
+		b.blocks = append(b.blocks, types.AnthropicContentBlock{Type: blockType})
+		b.blockOpen = true
+		last = len(b.blocks) - 1
+
+		b.send("content_block_start", gin.H{
+			"type":          "content_block_start",
+			"index":         last,
+			"content_block": gin.H{"type": blockType, blockType: ""},
+		})
+	}
+
+	delta := gin.H{"type": "text_delta", "text": text}
+	if blockType == "thinking" {
+		b.blocks[last].Thinking += text
+		delta = gin.H{"type": "thinking_delta", "thinking": text}
+	} else {
+		b.blocks[last].Text += text
+	}
+
+	b.send("content_block_delta", gin.H{
+		"type":  "content_block_delta",
+		"index": last,
+		"delta": delta,
+	})
+}
+
+// addToolUse adds a complete tool_use block
+func (b *anthropicMessageBuilder) addToolUse(toolCall types.ToolCall) {
+	b.closeBlock()
+
+	input := map[string]interface{}{}
+	if toolCall.Function.Arguments != "" {
+		if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &input); err != nil {
+			log.Printf("Failed to parse tool call arguments: %v", err)
+		}
+	}
+
+	b.blocks = append(b.blocks, types.AnthropicContentBlock{
+		Type:  "tool_use",
+		ID:    toolCall.ID,
+		Name:  toolCall.Function.Name,
+		Input: input,
+	})
+	b.blockOpen = true
+	index := len(b.blocks) - 1
+
+	b.send("content_block_start", gin.H{
+		"type":  "content_block_start",
+		"index": index,
+		"content_block": gin.H{
+			"type":  "tool_use",
+			"id":    toolCall.ID,
+			"name":  toolCall.Function.Name,
+			"input": gin.H{},
+		},
+	})
+	b.send("content_block_delta", gin.H{
+		"type":  "content_block_delta",
+		"index": index,
+		"delta": gin.H{"type": "input_json_delta", "partial_json": toolCall.Function.Arguments},
+	})
+	b.closeBlock()
+}
+
+// closeBlock closes the open content block, if any
+func (b *anthropicMessageBuilder) closeBlock() {
+	if !b.blockOpen {
+		return
+	}
+	b.blockOpen = false
+	b.send("content_block_stop", gin.H{
+		"type":  "content_block_stop",
+		"index": len(b.blocks) - 1,
+	})
+}
+
+// finish closes the message once the stream has ended
+func (b *anthropicMessageBuilder) finish() {
+	b.closeBlock()
+	if b.stopReason == "" {
+		b.stopReason = constants.StopReasonEndTurn
+	}
+
+	b.send("message_delta", gin.H{
+		"type": "message_delta",
+		"delta": gin.H{
+			"stop_reason":   b.stopReason,
+			"stop_sequence": nil,
+		},
+		"usage": b.anthropicUsage(),
+	})
+	b.send("message_stop", gin.H{"type": "message_stop"})
+}
+
+// send emits an SSE event when streaming, preceded by message_start on the first event
+func (b *anthropicMessageBuilder) send(event string, data interface{}) {
+	if b.emit == nil {
+		return
+	}
+
+	if !b.started {
+		b.started = true
+		message := b.response()
+		message.Content = []types.AnthropicContentBlock{}
+		message.StopReason = nil
+		b.emit("message_start", gin.H{"type": "message_start", "message": message})
+	}
+
+	b.emit(event, data)
+}
+
+// anthropicUsage returns the token usage in Anthropic format
+func (b *anthropicMessageBuilder) anthropicUsage() types.AnthropicUsage {
+	return types.AnthropicUsage{
+		InputTokens:  b.usage.InputTokens,
+		OutputTokens: b.usage.OutputTokens,
+	}
+}
+
+// response returns the assembled message
+func (b *anthropicMessageBuilder) response() types.AnthropicMessagesResponse {
+	var stopReason *string
+	if b.stopReason != "" {
+		stopReason = strPtr(b.stopReason)
+	}
+
+	return types.AnthropicMessagesResponse{
+		ID:         b.id,
+		Type:       "message",
+		Role:       "assistant",
+		Model:      b.model,
+		Content:    b.blocks,
+		StopReason: stopReason,
+		Usage:      b.anthropicUsage(),
+	}
+}
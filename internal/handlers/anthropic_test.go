@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gemini-cli-go/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropic_UnknownModel(t *testing.T) {
+	handler, engine := setupTestHandler()
+	anthropicHandler := NewAnthropicHandler(handler.config, handler.geminiClient)
+	engine.POST("/v1/messages", anthropicHandler.Messages)
+	engine.POST("/v1/messages/count_tokens", anthropicHandler.CountTokens)
+
+	tests := []struct {
+		name      string
+		path      string
+		body      string
+		status    int
+		errorType string
+	}{
+		{
+			name:      "messages",
+			path:      "/v1/messages",
+			body:      `{"model":"claude-unknown","max_tokens":16,"messages":[{"role":"user","content":"Hi"}]}`,
+			status:    http.StatusNotFound,
+			errorType: anthropicErrorTypeNotFound,
+		},
+		{
+			name:      "count tokens",
+			path:      "/v1/messages/count_tokens",
+			body:      `{"model":"claude-unknown","messages":[{"role":"user","content":"Hi"}]}`,
+			status:    http.StatusNotFound,
+			errorType: anthropicErrorTypeNotFound,
+		},
+		{
+			name:      "missing model",
+			path:      "/v1/messages",
+			body:      `{"max_tokens":16,"messages":[{"role":"user","content":"Hi"}]}`,
+			status:    http.StatusBadRequest,
+			errorType: anthropicErrorTypeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			var response types.AnthropicErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, "error", response.Type)
+			assert.Equal(t, tt.errorType, response.Error.Type)
+		})
+	}
+}
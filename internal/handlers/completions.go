@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/gemini"
+	"gemini-cli-go/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Completions handles POST /v1/completions
+func (h *OpenAIHandler) Completions(c *gin.Context) {
+	var req types.CompletionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, errorTypeInvalidRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if apiErr := validateModel(req.Model); apiErr != nil {
+		writeOpenAIError(c, apiErr.status, apiErr.errorType, apiErr.code, apiErr.message)
+		return
+	}
+
+	if apiErr := checkUnsupportedParams(h.config, unsupportedCompletionParams(req)); apiErr != nil {
+		writeOpenAIError(c, apiErr.status, apiErr.errorType, apiErr.code, apiErr.message)
+		return
+	}
+
+	prompts, err := completionPrompts(req.Prompt)
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, errorTypeInvalidRequest, "invalid_prompt", err.Error())
+		return
+	}
+
+	options := &gemini.StreamOptions{
+		Temperature:    req.Temperature,
+		TopP:           req.TopP,
+		MaxTokens:      req.MaxTokens,
+		StopSequences:  req.Stop,
+		CandidateCount: req.N,
+	}
+
+	if req.Stream {
+		h.streamCompletions(c, req, prompts, options)
+	} else {
+		h.getCompletions(c, req, prompts, options)
+	}
+}
+
+// unsupportedCompletionParams returns the completion request parameters that have no Gemini equivalent
+func unsupportedCompletionParams(req types.CompletionRequest) []string {
+	var params []string
+	if req.Logprobs != nil {
+		params = append(params, "logprobs")
+	}
+	if len(req.LogitBias) > 0 {
+		params = append(params, "logit_bias")
+	}
+	return params
+}
+
+// completionPrompts returns the prompts of a completion request, which may be a string or an array of strings
+func completionPrompts(prompt interface{}) ([]string, error) {
+	switch p := prompt.(type) {
+	case nil:
+		return []string{""}, nil
+	case string:
+		return []string{p}, nil
+	case []interface{}:
+		if len(p) == 0 {
+			return []string{""}, nil
+		}
+		prompts := make([]string, 0, len(p))
+		for _, item := range p {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("prompt must be a string or an array of strings, token arrays are not supported")
+			}
+			prompts = append(prompts, text)
+		}
+		return prompts, nil
+	default:
+		return nil, fmt.Errorf("prompt must be a string or an array of strings")
+	}
+}
+
+// completionMessages turns a prompt into a single user turn, wrapping it with the suffix for insertion
+func completionMessages(prompt string, suffix string) []types.ChatMessage {
+	if suffix == "" {
+		return []types.ChatMessage{
+			{Role: "system", Content: constants.CompletionSystemPrompt},
+			{Role: "user", Content: prompt},
+		}
+	}
+
+	return []types.ChatMessage{
+		{Role: "system", Content: constants.CompletionInsertSystemPrompt},
+		{Role: "user", Content: "<prefix>" + prompt + "</prefix><suffix>" + suffix + "</suffix>"},
+	}
+}
+
+// completionCount returns the number of choices requested per prompt, choices are grouped by prompt like OpenAI's
+func completionCount(req types.CompletionRequest) int {
+	if req.N != nil && *req.N > 1 {
+		return *req.N
+	}
+	return 1
+}
+
+func (h *OpenAIHandler) getCompletions(c *gin.Context, req types.CompletionRequest, prompts []string, options *gemini.StreamOptions) {
+	response := types.CompletionResponse{
+		ID:      constants.CompletionIDPrefix + uuid.New().String(),
+		Object:  constants.OpenAITextCompletionObject,
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: make([]types.CompletionChoice, 0, len(prompts)*completionCount(req)),
+		Usage:   &types.ChatCompletionUsage{},
+	}
+
+	for i, prompt := range prompts {
+		result, err := h.geminiClient.GetCompletion(c.Request.Context(), req.Model, completionMessages(prompt, req.Suffix), options)
+		if err != nil {
+			writeClientError(c, err)
+			return
+		}
+
+		response.Model = result.Model
+		for _, candidate := range result.Candidates {
+			response.Choices = append(response.Choices, types.CompletionChoice{
+				Text:         candidate.Content,
+				Index:        i*completionCount(req) + candidate.Index,
+				FinishReason: strPtr(candidate.FinishReason),
+			})
+		}
+		if result.Usage != nil {
+			response.Usage.PromptTokens += result.Usage.InputTokens
+			response.Usage.CompletionTokens += result.Usage.OutputTokens
+			response.Usage.TotalTokens += result.Usage.InputTokens + result.Usage.OutputTokens
+		}
+	}
+
+	if response.Model != req.Model {
+		c.Header(constants.HeaderGeminiFallback, response.Model)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// streamCompletions streams the choices of each prompt in turn as legacy completion chunks
+func (h *OpenAIHandler) streamCompletions(c *gin.Context, req types.CompletionRequest, prompts []string, options *gemini.StreamOptions) {
+	setStreamDeadline(c, h.config.GetStreamMaxDuration())
+	id := constants.CompletionIDPrefix + uuid.New().String()
+	created := time.Now().Unix()
+	model := req.Model
+
+	write := func(data interface{}) {
+		if !c.Writer.Written() {
+			c.Writer.Header().Set("Content-Type", constants.ContentTypeSSE)
+			c.Writer.Header().Set("Cache-Control", "no-cache")
+			c.Writer.Header().Set("Connection", "keep-alive")
+		}
+		body, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("Failed to marshal completion chunk: %v", err)
+			return
+		}
+		fmt.Fprintf(c.Writer, "%s%s\n\n", constants.SSEDataPrefix, body)
+		c.Writer.Flush()
+	}
+
+	writeChoice := func(index int, text string, finishReason *string) {
+		write(types.CompletionResponse{
+			ID:      id,
+			Object:  constants.OpenAITextCompletionObject,
+			Created: created,
+			Model:   model,
+			Choices: []types.CompletionChoice{{Text: text, Index: index, FinishReason: finishReason}},
+		})
+	}
+
+	for i, prompt := range prompts {
+		chunkChan, err := h.geminiClient.StreamContent(c.Request.Context(), req.Model, "", completionMessages(prompt, req.Suffix), options)
+		if err == nil {
+			chunkChan = withHeartbeats(c.Request.Context(), chunkChan, h.config.GetSSEHeartbeatInterval())
+			err = h.streamCompletionChoices(c, i*completionCount(req), chunkChan, &model, writeChoice)
+		}
+		if err != nil {
+			log.Printf("Completion stream failed: %v", err)
+
+			// Errors before the first chunk still get a proper HTTP status
+			if !c.Writer.Written() {
+				writeClientError(c, err)
+				return
+			}
+			apiErr := classifyError(err)
+			write(newOpenAIErrorResponse(apiErr.errorType, apiErr.code, apiErr.message))
+			return
+		}
+	}
+
+	if !c.Writer.Written() {
+		c.Writer.Header().Set("Content-Type", constants.ContentTypeSSE)
+	}
+	fmt.Fprintf(c.Writer, "%s\n\n", constants.SSEDoneMessage)
+	c.Writer.Flush()
+}
+
+// streamCompletionChoices streams the choices of a single prompt, numbered from first, returning the
+// error that ended the stream
+func (h *OpenAIHandler) streamCompletionChoices(c *gin.Context, first int, chunkChan <-chan types.StreamChunk, model *string, writeChoice func(int, string, *string)) error {
+	for chunk := range chunkChan {
+		switch chunk.Type {
+		case types.StreamChunkTypeError:
+			err, ok := chunk.Data.(error)
+			if !ok {
+				err = fmt.Errorf("%v", chunk.Data)
+			}
+			return err
+
+		case types.StreamChunkTypeModel:
+			// A fallback model answers, headers can still be set if nothing was written yet
+			if fallback, ok := chunk.Data.(string); ok {
+				if !c.Writer.Written() {
+					c.Header(constants.HeaderGeminiFallback, fallback)
+				}
+				*model = fallback
+			}
+
+		case types.StreamChunkTypeHeartbeat:
+			writeSSEHeartbeat(c)
+
+		case types.StreamChunkTypeText:
+			if text, ok := chunk.Data.(string); ok && text != "" {
+				writeChoice(first+chunk.Index, text, nil)
+			}
+
+		case types.StreamChunkTypeFinishReason:
+			if finish, ok := chunk.Data.(types.FinishData); ok {
+				writeChoice(first+chunk.Index, "", strPtr(finish.FinishReason))
+			}
+		}
+	}
+	return nil
+}
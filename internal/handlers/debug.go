@@ -7,6 +7,7 @@ import (
 	"gemini-cli-go/internal/auth"
 	"gemini-cli-go/internal/config"
 	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/metrics"
 	"gemini-cli-go/internal/types"
 
 	"github.com/gin-gonic/gin"
@@ -111,7 +112,7 @@ func (h *DebugHandler) ServiceInfo(c *gin.Context) {
 
 	authType := "None"
 	if requiresAuth {
-		authType = "Bearer token in Authorization header"
+		authType = "Bearer token in Authorization header or x-api-key header"
 	}
 
 	response := types.ServiceInfoResponse{
@@ -124,6 +125,7 @@ func (h *DebugHandler) ServiceInfo(c *gin.Context) {
 		},
 		Endpoints: types.EndpointInfo{
 			ChatCompletions: constants.PathV1 + constants.PathChatCompletions,
+			Messages:        constants.PathV1 + constants.PathMessages,
 			Models:          constants.PathV1 + constants.PathModels,
 			Debug: types.DebugRoutes{
 				Cache:     constants.PathV1 + constants.PathDebugCache,
@@ -198,13 +200,15 @@ func (h *DebugHandler) SystemStatus(c *gin.Context) {
 			"error":      getErrorString(authErr),
 			"cache_info": cacheInfo,
 		},
+		"accounts": h.authManager.AccountStatuses(),
 		"configuration": map[string]interface{}{
 			"fake_thinking":              h.config.IsFakeThinkingEnabled(),
 			"real_thinking":              h.config.IsRealThinkingEnabled(),
 			"stream_thinking_as_content": h.config.IsStreamThinkingAsContent(),
-			"api_key_required":          h.config.IsAuthRequired(),
-			"project_id_configured":     h.config.GetGeminiProjectID() != "",
-			"gcp_credentials_provided":  h.config.GetGCPServiceAccount() != "",
+			"api_key_required":           h.config.IsAuthRequired(),
+			"project_id_configured":      h.config.GetGeminiProjectID() != "",
+			"gcp_credentials_provided":   h.config.GetGCPServiceAccount() != "",
+			"account_strategy":           h.config.GetAccountStrategy(),
 		},
 	}
 
@@ -251,6 +255,7 @@ func (h *DebugHandler) Metrics(c *gin.Context) {
 			"cache_hits":         "not_implemented", // Would track cache hits
 			"auth_failures":      "not_implemented", // Would track auth failures
 		},
+		"counters": metrics.Snapshot(),
 		"health": map[string]interface{}{
 			"uptime":        "not_implemented", // Would track uptime
 			"memory_usage":  "not_implemented", // Would track memory usage
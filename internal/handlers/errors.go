@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gemini-cli-go/internal/gemini"
+	"gemini-cli-go/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAI error types
+const (
+	errorTypeInvalidRequest  = "invalid_request_error"
+	errorTypeInvalidResponse = "invalid_response_error"
+	errorTypeRateLimit       = "rate_limit_error"
+	errorTypeAPI             = "api_error"
+)
+
+// apiError describes how an error is reported to OpenAI clients
+type apiError struct {
+	status     int
+	errorType  string
+	code       string
+	message    string
+	retryAfter time.Duration
+}
+
+// newInvalidRequestError creates the error reported for an invalid request parameter
+func newInvalidRequestError(code string, message string) *apiError {
+	return &apiError{
+		status:    http.StatusBadRequest,
+		errorType: errorTypeInvalidRequest,
+		code:      code,
+		message:   message,
+	}
+}
+
+// classifyError maps an error from the Gemini client to an HTTP status and OpenAI error
+func classifyError(err error) apiError {
+	result := apiError{
+		status:    http.StatusInternalServerError,
+		errorType: errorTypeAPI,
+		code:      "internal_error",
+		message:   err.Error(),
+	}
+
+	var blockedErr *gemini.PromptBlockedError
+	var invalidErr *gemini.InvalidRequestError
+	var upstreamErr *gemini.UpstreamError
+
+	switch {
+	case errors.As(err, &blockedErr):
+		result.status = http.StatusBadRequest
+		result.errorType = errorTypeInvalidRequest
+		result.code = "content_policy_violation"
+
+	case errors.As(err, &invalidErr):
+		result.status = http.StatusBadRequest
+		result.errorType = errorTypeInvalidRequest
+		result.code = "invalid_request"
+
+	case errors.As(err, &upstreamErr):
+		result.message = upstreamErr.Message
+		switch {
+		case upstreamErr.StatusCode == http.StatusTooManyRequests:
+			result.status = http.StatusTooManyRequests
+			result.errorType = errorTypeRateLimit
+			result.code = "rate_limit_exceeded"
+			result.retryAfter = upstreamErr.RetryAfter
+		case upstreamErr.StatusCode == http.StatusBadRequest:
+			result.status = http.StatusBadRequest
+			result.errorType = errorTypeInvalidRequest
+			result.code = "invalid_request"
+		case upstreamErr.IsTimeout():
+			result.status = http.StatusGatewayTimeout
+			result.code = "upstream_timeout"
+		default:
+			result.status = http.StatusBadGateway
+			result.code = "upstream_error"
+		}
+
+	case errors.Is(err, context.DeadlineExceeded):
+		result.status = http.StatusGatewayTimeout
+		result.code = "upstream_timeout"
+	}
+
+	return result
+}
+
+// writeOpenAIError writes an error response in OpenAI format
+func writeOpenAIError(c *gin.Context, status int, errorType string, code string, message string) {
+	c.JSON(status, newOpenAIErrorResponse(errorType, code, message))
+}
+
+// writeClientError writes a Gemini client error with the matching HTTP status
+func writeClientError(c *gin.Context, err error) {
+	apiErr := classifyError(err)
+	if apiErr.retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.retryAfter.Seconds()))))
+	}
+	writeOpenAIError(c, apiErr.status, apiErr.errorType, apiErr.code, apiErr.message)
+}
+
+// newOpenAIErrorResponse creates an error body in OpenAI format
+func newOpenAIErrorResponse(errorType string, code string, message string) types.OpenAIErrorResponse {
+	apiError := types.OpenAIError{
+		Message: message,
+		Type:    errorType,
+	}
+	if code != "" {
+		apiError.Code = &code
+	}
+
+	return types.OpenAIErrorResponse{Error: apiError}
+}
+
+// Anthropic error types
+const (
+	anthropicErrorTypeInvalidRequest = "invalid_request_error"
+	anthropicErrorTypeNotFound       = "not_found_error"
+	anthropicErrorTypeRateLimit      = "rate_limit_error"
+	anthropicErrorTypeAPI            = "api_error"
+	anthropicErrorTypeTimeout        = "timeout_error"
+)
+
+// anthropicErrorType maps a classified error to the matching Anthropic error type
+func anthropicErrorType(apiErr apiError) string {
+	switch {
+	case apiErr.errorType == errorTypeInvalidRequest:
+		return anthropicErrorTypeInvalidRequest
+	case apiErr.errorType == errorTypeRateLimit:
+		return anthropicErrorTypeRateLimit
+	case apiErr.status == http.StatusGatewayTimeout:
+		return anthropicErrorTypeTimeout
+	}
+	return anthropicErrorTypeAPI
+}
+
+// writeAnthropicError writes an error response in Anthropic format
+func writeAnthropicError(c *gin.Context, status int, errorType string, message string) {
+	c.JSON(status, newAnthropicErrorResponse(errorType, message))
+}
+
+// writeAnthropicClientError writes a Gemini client error in Anthropic format with the matching HTTP status
+func writeAnthropicClientError(c *gin.Context, err error) {
+	apiErr := classifyError(err)
+	if apiErr.retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.retryAfter.Seconds()))))
+	}
+	writeAnthropicError(c, apiErr.status, anthropicErrorType(apiErr), apiErr.message)
+}
+
+// newAnthropicErrorResponse creates an error body in Anthropic format
+func newAnthropicErrorResponse(errorType string, message string) types.AnthropicErrorResponse {
+	return types.AnthropicErrorResponse{
+		Type: "error",
+		Error: types.AnthropicError{
+			Type:    errorType,
+			Message: message,
+		},
+	}
+}
+
+// googleStatus returns the Google RPC status matching an HTTP status code
+func googleStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return "UNAVAILABLE"
+	case http.StatusGatewayTimeout:
+		return "DEADLINE_EXCEEDED"
+	}
+	return "INTERNAL"
+}
+
+// writeGoogleError writes an error response in Google API format
+func writeGoogleError(c *gin.Context, status int, message string) {
+	c.JSON(status, newGoogleErrorResponse(status, "", message))
+}
+
+// writeGoogleClientError writes a Gemini client error in Google API format, keeping the upstream status
+func writeGoogleClientError(c *gin.Context, err error) {
+	apiErr := classifyError(err)
+	if apiErr.retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(apiErr.retryAfter.Seconds()))))
+	}
+	c.JSON(apiErr.status, newGoogleErrorResponse(apiErr.status, upstreamStatus(err), apiErr.message))
+}
+
+// upstreamStatus returns the Google RPC status reported by the upstream, if any
+func upstreamStatus(err error) string {
+	var upstreamErr *gemini.UpstreamError
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.Status
+	}
+	return ""
+}
+
+// newGoogleErrorResponse creates an error body in Google API format
+func newGoogleErrorResponse(code int, status string, message string) types.GoogleErrorResponse {
+	if status == "" {
+		status = googleStatus(code)
+	}
+	return types.GoogleErrorResponse{
+		Error: types.GoogleError{
+			Code:    code,
+			Message: message,
+			Status:  status,
+		},
+	}
+}
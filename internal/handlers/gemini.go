@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"gemini-cli-go/internal/config"
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/gemini"
+	"gemini-cli-go/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeminiHandler handles native Gemini API requests, forwarding them untranslated
+type GeminiHandler struct {
+	config       *config.Config
+	geminiClient *gemini.Client
+}
+
+// NewGeminiHandler creates a new native Gemini handler
+func NewGeminiHandler(config *config.Config, geminiClient *gemini.Client) *GeminiHandler {
+	return &GeminiHandler{
+		config:       config,
+		geminiClient: geminiClient,
+	}
+}
+
+// ModelAction handles POST /v1beta/models/{model}:generateContent, :streamGenerateContent and :countTokens
+func (h *GeminiHandler) ModelAction(c *gin.Context) {
+	model, method, found := strings.Cut(c.Param("modelAction"), ":")
+	if !found || model == "" {
+		writeGoogleError(c, http.StatusNotFound, fmt.Sprintf("unknown method: %s", c.Param("modelAction")))
+		return
+	}
+
+	var request map[string]interface{}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		writeGoogleError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// countTokens accepts either contents or a full generateContentRequest
+	if method == gemini.NativeMethodCountTokens {
+		if generateRequest, ok := request["generateContentRequest"].(map[string]interface{}); ok {
+			request = map[string]interface{}{"contents": generateRequest["contents"]}
+		}
+	}
+
+	chunkChan, err := h.geminiClient.ForwardNative(c.Request.Context(), model, method, request)
+	if err != nil {
+		writeGoogleClientError(c, err)
+		return
+	}
+
+	if method == gemini.NativeMethodStreamGenerateContent {
+		h.streamResponses(c, chunkChan)
+	} else {
+		h.writeResponse(c, chunkChan)
+	}
+}
+
+// writeResponse writes the single response of a unary method
+func (h *GeminiHandler) writeResponse(c *gin.Context, chunkChan <-chan types.StreamChunk) {
+	for chunk := range chunkChan {
+		switch chunk.Type {
+		case types.StreamChunkTypeError:
+			if err, ok := chunk.Data.(error); ok {
+				log.Printf("Native request failed: %v", err)
+				writeGoogleClientError(c, err)
+			}
+			return
+		case types.StreamChunkTypeRaw:
+			if raw, ok := chunk.Data.(json.RawMessage); ok {
+				c.Data(http.StatusOK, constants.ContentTypeJSON, raw)
+			}
+		}
+	}
+}
+
+// streamResponses writes streamed responses as SSE with ?alt=sse, and as a JSON array otherwise
+func (h *GeminiHandler) streamResponses(c *gin.Context, chunkChan <-chan types.StreamChunk) {
+	sse := c.Query("alt") == "sse"
+	started := false
+
+	start := func() {
+		if started {
+			if !sse {
+				c.Writer.WriteString(",\r\n")
+			}
+			return
+		}
+		started = true
+		if sse {
+			c.Writer.Header().Set("Content-Type", constants.ContentTypeSSE)
+			c.Writer.Header().Set("Cache-Control", "no-cache")
+			c.Writer.Header().Set("Connection", "keep-alive")
+		} else {
+			c.Writer.Header().Set("Content-Type", constants.ContentTypeJSON)
+			c.Writer.WriteString("[")
+		}
+	}
+
+	write := func(data []byte) {
+		start()
+		if sse {
+			fmt.Fprintf(c.Writer, "%s%s\r\n\r\n", constants.SSEDataPrefix, data)
+		} else {
+			c.Writer.Write(data)
+		}
+		c.Writer.Flush()
+	}
+
+	setStreamDeadline(c, h.config.GetStreamMaxDuration())
+
+	// Heartbeats are SSE comments, the JSON array form has no room for them
+	if sse {
+		chunkChan = withHeartbeats(c.Request.Context(), chunkChan, h.config.GetSSEHeartbeatInterval())
+	}
+
+	for chunk := range chunkChan {
+		switch chunk.Type {
+		case types.StreamChunkTypeHeartbeat:
+			started = true
+			writeSSEHeartbeat(c)
+
+		case types.StreamChunkTypeError:
+			err, ok := chunk.Data.(error)
+			if !ok {
+				err = fmt.Errorf("%v", chunk.Data)
+			}
+			log.Printf("Native stream failed: %v", err)
+
+			// Errors before the first response still get a proper HTTP status
+			if !started {
+				writeGoogleClientError(c, err)
+				return
+			}
+			apiErr := classifyError(err)
+			body, _ := json.Marshal(newGoogleErrorResponse(apiErr.status, upstreamStatus(err), apiErr.message))
+			write(body)
+			if !sse {
+				c.Writer.WriteString("]")
+			}
+			return
+
+		case types.StreamChunkTypeRaw:
+			if raw, ok := chunk.Data.(json.RawMessage); ok {
+				write(raw)
+			}
+		}
+	}
+
+	if !started {
+		start()
+	}
+	if !sse {
+		c.Writer.WriteString("]")
+	}
+	c.Writer.Flush()
+}
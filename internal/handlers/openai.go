@@ -1,12 +1,17 @@
 package handlers
 
 import (
+	"fmt"
 	"gemini-cli-go/internal/auth"
 	"gemini-cli-go/internal/config"
+	"gemini-cli-go/internal/constants"
 	"gemini-cli-go/internal/gemini"
 	"gemini-cli-go/internal/models"
+	"gemini-cli-go/internal/stream"
 	"gemini-cli-go/internal/types"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -38,7 +43,12 @@ func (h *OpenAIHandler) ListModels(c *gin.Context) {
 func (h *OpenAIHandler) ChatCompletions(c *gin.Context) {
 	var req types.ChatCompletionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeOpenAIError(c, http.StatusBadRequest, errorTypeInvalidRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if apiErr := h.validateChatRequest(req); apiErr != nil {
+		writeOpenAIError(c, apiErr.status, apiErr.errorType, apiErr.code, apiErr.message)
 		return
 	}
 
@@ -54,75 +64,386 @@ func (h *OpenAIHandler) ChatCompletions(c *gin.Context) {
 	}
 }
 
+// validateModel checks that a requested model is known, returning the error to report if not
+func validateModel(model string) *apiError {
+	// Model suffixes like :search are resolved by the Gemini client
+	modelID, _, _ := strings.Cut(model, ":")
+	if !models.IsValidModel(modelID) {
+		return newInvalidRequestError("model_not_found", fmt.Sprintf("model '%s' not found", model))
+	}
+	return nil
+}
+
+// validateChatRequest checks the parameters of a chat completion request, returning the error to report if any
+func (h *OpenAIHandler) validateChatRequest(req types.ChatCompletionRequest) *apiError {
+	if len(req.Messages) == 0 {
+		return newInvalidRequestError("invalid_request", "messages is a required field")
+	}
+
+	if apiErr := validateModel(req.Model); apiErr != nil {
+		return apiErr
+	}
+
+	if err := h.validateImageSupport(&req); err != nil {
+		return newInvalidRequestError("invalid_request", err.Error())
+	}
+
+	if err := config.ValidateSafetySettings(req.SafetySettings); err != nil {
+		return newInvalidRequestError("invalid_safety_settings", err.Error())
+	}
+
+	if req.ReasoningFormat != "" && !config.IsValidReasoningFormat(req.ReasoningFormat) {
+		return newInvalidRequestError("invalid_reasoning_format", fmt.Sprintf("unsupported reasoning_format: %s", req.ReasoningFormat))
+	}
+
+	if req.CodeExecutionFormat != "" && !config.IsValidCodeExecutionFormat(req.CodeExecutionFormat) {
+		return newInvalidRequestError("invalid_code_execution_format", fmt.Sprintf("unsupported code_execution_format: %s", req.CodeExecutionFormat))
+	}
+
+	return checkUnsupportedParams(h.config, unsupportedParams(req))
+}
+
+// checkUnsupportedParams rejects a request using parameters Gemini cannot honour when
+// UNSUPPORTED_PARAMS is reject, and logs that they are ignored otherwise
+func checkUnsupportedParams(config *config.Config, params []string) *apiError {
+	if len(params) == 0 {
+		return nil
+	}
+
+	message := fmt.Sprintf("unsupported parameters: %s", strings.Join(params, ", "))
+	if config.RejectUnsupportedParams() {
+		return newInvalidRequestError("unsupported_parameter", message)
+	}
+	log.Printf("Ignoring %s", message)
+	return nil
+}
+
+// validateImageSupport rejects image inputs for models that cannot read them
+func (h *OpenAIHandler) validateImageSupport(req *types.ChatCompletionRequest) error {
+	modelID, _, _ := strings.Cut(req.Model, ":")
+	for _, msg := range req.Messages {
+		parts, ok := msg.Content.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, part := range parts {
+			if partMap, ok := part.(map[string]interface{}); ok && partMap["type"] == "image_url" {
+				return models.ValidateModelForImages(modelID)
+			}
+		}
+	}
+	return nil
+}
+
+// unsupportedParams returns the request parameters that have no Gemini equivalent
+func unsupportedParams(req types.ChatCompletionRequest) []string {
+	var params []string
+	if req.Logprobs != nil && *req.Logprobs {
+		params = append(params, "logprobs")
+	}
+	if req.TopLogprobs != nil {
+		params = append(params, "top_logprobs")
+	}
+	if len(req.LogitBias) > 0 {
+		params = append(params, "logit_bias")
+	}
+	return params
+}
+
+// streamOptions builds the Gemini stream options for a chat completion request
+func (h *OpenAIHandler) streamOptions(req types.ChatCompletionRequest) *gemini.StreamOptions {
+	// include_reasoning overrides whether Gemini's thoughts are returned for this request
+	includeReasoning := h.config.IsRealThinkingEnabled()
+	fakeThinking := h.config.IsFakeThinkingEnabled()
+	if req.IncludeReasoning != nil {
+		includeReasoning = *req.IncludeReasoning
+		fakeThinking = fakeThinking && *req.IncludeReasoning
+	}
+
+	// max_completion_tokens supersedes the deprecated max_tokens
+	maxTokens := req.MaxTokens
+	if req.MaxCompletionTokens != nil {
+		maxTokens = req.MaxCompletionTokens
+	}
+
+	return &gemini.StreamOptions{
+		Temperature:             req.Temperature,
+		MaxTokens:               maxTokens,
+		TopP:                    req.TopP,
+		TopK:                    req.TopK,
+		ThinkingBudget:          req.ThinkingBudget,
+		StopSequences:           req.Stop,
+		Seed:                    req.Seed,
+		PresencePenalty:         req.PresencePenalty,
+		FrequencyPenalty:        req.FrequencyPenalty,
+		CandidateCount:          req.N,
+		ReasoningEffort:         req.ReasoningEffort,
+		EnableRealThinking:      includeReasoning,
+		EnableFakeThinking:      fakeThinking,
+		StreamThinkingAsContent: h.config.IsStreamThinkingAsContent(),
+		Tools:                   req.Tools,
+		ToolChoice:              req.ToolChoice,
+		ParallelToolCalls:       req.ParallelToolCalls,
+		ResponseFormat:          req.ResponseFormat,
+		SafetySettings:          req.SafetySettings,
+		CodeExecutionFormat:     h.codeExecutionFormat(req),
+	}
+}
+
 func (h *OpenAIHandler) getChatCompletion(c *gin.Context, req types.ChatCompletionRequest) {
-	result, err := h.geminiClient.GetCompletion(c.Request.Context(), req.Model, req.Messages, &gemini.StreamOptions{
-		EnableFakeThinking: h.config.IsFakeThinkingEnabled(),
-	})
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	options := h.streamOptions(req)
+
+	var result *gemini.CompletionResult
+	var formatErr error
+
+	// Retry when the output does not match the requested response format
+	for attempt := 0; attempt <= h.config.GetSchemaValidationRetries(); attempt++ {
+		var err error
+		result, err = h.geminiClient.GetCompletion(c.Request.Context(), req.Model, req.Messages, options)
+		if err != nil {
+			writeClientError(c, err)
+			return
+		}
+
+		formatErr = validateCandidates(req.ResponseFormat, result.Candidates)
+		if formatErr == nil {
+			break
+		}
+		log.Printf("Response format validation failed (attempt %d): %v", attempt+1, formatErr)
+	}
+
+	if formatErr != nil {
+		apiErr := responseFormatMismatchError(formatErr)
+		writeOpenAIError(c, apiErr.status, apiErr.errorType, apiErr.code, apiErr.message)
 		return
 	}
 
+	if result.Model != req.Model {
+		c.Header(constants.HeaderGeminiFallback, result.Model)
+	}
+
+	choices := make([]types.ChatCompletionChoice, 0, len(result.Candidates))
+	for _, candidate := range result.Candidates {
+		message := &types.ChatCompletionMessage{
+			Role:          "assistant",
+			Content:       candidate.Content,
+			ToolCalls:     candidate.ToolCalls,
+			Annotations:   candidate.Annotations,
+			CodeExecution: candidate.CodeExecution,
+		}
+		setReasoning(message, candidate.Reasoning, h.reasoningFormat(req))
+
+		choices = append(choices, types.ChatCompletionChoice{
+			Index:         candidate.Index,
+			Message:       message,
+			FinishReason:  strPtr(candidate.FinishReason),
+			SafetyRatings: candidate.SafetyRatings,
+		})
+	}
+
+	var usage types.UsageData
+	if result.Usage != nil {
+		usage = *result.Usage
+	}
+
 	response := types.ChatCompletionResponse{
 		ID:      "chatcmpl-" + uuid.New().String(),
 		Object:  "chat.completion",
 		Created: time.Now().Unix(),
-		Model:   req.Model,
-		Choices: []types.ChatCompletionChoice{
-			{
-				Index: 0,
-				Message: &types.ChatCompletionMessage{
-					Role:    "assistant",
-					Content: result.Content,
-				},
-				FinishReason: strPtr("stop"),
-			},
-		},
-		Usage: &types.ChatCompletionUsage{
-			PromptTokens:     result.Usage.InputTokens,
-			CompletionTokens: result.Usage.OutputTokens,
-			TotalTokens:      result.Usage.InputTokens + result.Usage.OutputTokens,
-		},
+		Model:   result.Model,
+		Choices: choices,
+		Usage:   usage.ChatCompletionUsage(),
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// streamChatCompletions streams a chat completion as OpenAI chat.completion.chunk events
 func (h *OpenAIHandler) streamChatCompletions(c *gin.Context, req types.ChatCompletionRequest) {
-	c.Writer.Header().Set("Content-Type", "text/event-stream")
-	c.Writer.Header().Set("Cache-Control", "no-cache")
-	c.Writer.Header().Set("Connection", "keep-alive")
-	c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-
-	chunkChan, err := h.geminiClient.StreamContent(c.Request.Context(), req.Model, "", req.Messages, &gemini.StreamOptions{
-		EnableFakeThinking: h.config.IsFakeThinkingEnabled(),
-	})
+	chunkChan, err := h.geminiClient.StreamContent(c.Request.Context(), req.Model, "", req.Messages, h.streamOptions(req))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeClientError(c, err)
+		return
+	}
+
+	setStreamDeadline(c, h.config.GetStreamMaxDuration())
+	writer := stream.NewStreamWriter(newGinResponseWriter(c), req.Model)
+	writer.Transformer().SetReasoningFormat(h.reasoningFormat(req))
+	streamed := newStreamedCandidates(req.ResponseFormat)
+
+	for chunk := range withHeartbeats(c.Request.Context(), chunkChan, h.config.GetSSEHeartbeatInterval()) {
+		switch chunk.Type {
+		case types.StreamChunkTypeError:
+			err, ok := chunk.Data.(error)
+			if !ok {
+				err = fmt.Errorf("%v", chunk.Data)
+			}
+			log.Printf("Stream failed: %v", err)
+
+			// Errors before the first chunk still get a proper HTTP status,
+			// afterwards the error is reported in-band and ends the stream
+			if !writer.HasStarted() {
+				writeClientError(c, err)
+				return
+			}
+			apiErr := classifyError(err)
+			if err := writer.WriteData(newOpenAIErrorResponse(apiErr.errorType, apiErr.code, apiErr.message)); err != nil {
+				log.Printf("Failed to write stream error: %v", err)
+			}
+			return
+
+		case types.StreamChunkTypeModel:
+			// A fallback model answers, headers can still be set if nothing was written yet
+			if model, ok := chunk.Data.(string); ok {
+				if !writer.HasStarted() {
+					c.Header(constants.HeaderGeminiFallback, model)
+				}
+				writer.Transformer().SetModel(model)
+			}
+
+		case types.StreamChunkTypeHeartbeat:
+			if err := writer.WriteHeartbeat(); err != nil {
+				log.Printf("Failed to write heartbeat: %v", err)
+			}
+
+		default:
+			streamed.add(chunk)
+			if err := writer.WriteChunk(chunk); err != nil {
+				log.Printf("Failed to write stream chunk: %v", err)
+			}
+		}
+	}
+
+	// The output has been streamed already, so a response format mismatch can only be reported in-band
+	if err := streamed.validate(); err != nil {
+		apiErr := responseFormatMismatchError(err)
+		if err := writer.WriteData(newOpenAIErrorResponse(apiErr.errorType, apiErr.code, apiErr.message)); err != nil {
+			log.Printf("Failed to write stream error: %v", err)
+		}
 		return
 	}
 
-	for chunk := range chunkChan {
-		response := types.ChatCompletionResponse{
-			ID:      "chatcmpl-" + uuid.New().String(),
-			Object:  "chat.completion.chunk",
-			Created: time.Now().Unix(),
-			Model:   req.Model,
-			Choices: []types.ChatCompletionChoice{
-				{
-					Index: 0,
-					Delta: &types.ChatCompletionDelta{
-						Content: chunk.Data.(string),
-					},
-				},
-			},
+	// Like OpenAI, usage is only streamed on request, in a final chunk without choices
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		if err := writer.WriteUsageChunk(); err != nil {
+			log.Printf("Failed to write usage chunk: %v", err)
 		}
-		c.SSEEvent("data", response)
-		c.Writer.Flush()
 	}
 
-	c.SSEEvent("data", "[DONE]")
-	c.Writer.Flush()
+	if err := writer.WriteFinalChunk(); err != nil {
+		log.Printf("Failed to write final chunk: %v", err)
+	}
+}
+
+// reasoningFormat returns where reasoning is placed for a request, reasoning_format overrides the configured default
+func (h *OpenAIHandler) reasoningFormat(req types.ChatCompletionRequest) string {
+	if req.ReasoningFormat != "" {
+		return req.ReasoningFormat
+	}
+	return h.config.GetReasoningFormat()
+}
+
+// codeExecutionFormat returns how code execution parts are returned for the request
+func (h *OpenAIHandler) codeExecutionFormat(req types.ChatCompletionRequest) string {
+	if req.CodeExecutionFormat != "" {
+		return req.CodeExecutionFormat
+	}
+	return h.config.GetCodeExecutionFormat()
+}
+
+// setReasoning places the reasoning of a non-streaming message according to the reasoning format
+func setReasoning(message *types.ChatCompletionMessage, reasoning string, format string) {
+	if reasoning == "" {
+		return
+	}
+
+	switch format {
+	case constants.ReasoningFormatReasoningContent:
+		message.ReasoningContent = reasoning
+	case constants.ReasoningFormatThinkTags:
+		message.Content = constants.ThinkOpenTag + reasoning + constants.ThinkCloseTag + message.Content
+	default:
+		message.Reasoning = reasoning
+	}
+}
+
+// responseFormatMismatchError creates the error reported for output that does not match the response format
+func responseFormatMismatchError(err error) *apiError {
+	return &apiError{
+		status:    http.StatusBadGateway,
+		errorType: errorTypeInvalidResponse,
+		code:      "response_format_mismatch",
+		message:   fmt.Sprintf("%s: %v", constants.ErrResponseFormatMismatch, err),
+	}
+}
+
+// streamedCandidates collects the content and tool calls of streamed choices, so they can be
+// checked against the response format once the stream ends
+type streamedCandidates struct {
+	format    *types.ResponseFormat
+	content   []*strings.Builder
+	toolCalls [][]types.ToolCall
+}
+
+// newStreamedCandidates returns a collector for the response format, or nil when the format
+// needs no validation and the stream does not have to be buffered
+func newStreamedCandidates(format *types.ResponseFormat) *streamedCandidates {
+	if !gemini.RequiresResponseValidation(format) {
+		return nil
+	}
+	return &streamedCandidates{format: format}
+}
+
+// add records a text or tool call chunk
+func (s *streamedCandidates) add(chunk types.StreamChunk) {
+	if s == nil {
+		return
+	}
+	for len(s.content) <= chunk.Index {
+		s.content = append(s.content, &strings.Builder{})
+		s.toolCalls = append(s.toolCalls, nil)
+	}
+
+	switch chunk.Type {
+	case types.StreamChunkTypeText:
+		if text, ok := chunk.Data.(string); ok {
+			s.content[chunk.Index].WriteString(text)
+		}
+	case types.StreamChunkTypeToolCall:
+		if toolCall, ok := chunk.Data.(types.ToolCall); ok {
+			s.toolCalls[chunk.Index] = append(s.toolCalls[chunk.Index], toolCall)
+		}
+	}
+}
+
+// validate checks the streamed choices against the response format
+func (s *streamedCandidates) validate() error {
+	if s == nil {
+		return nil
+	}
+	candidates := make([]gemini.CandidateResult, len(s.content))
+	for i := range s.content {
+		candidates[i] = gemini.CandidateResult{Index: i, Content: s.content[i].String(), ToolCalls: s.toolCalls[i]}
+	}
+	return validateCandidates(s.format, candidates)
+}
+
+// validateCandidates checks that every candidate matches the requested response format
+func validateCandidates(format *types.ResponseFormat, candidates []gemini.CandidateResult) error {
+	for _, candidate := range candidates {
+		// Turns that only call tools carry no structured output
+		if len(candidate.ToolCalls) > 0 {
+			continue
+		}
+
+		if err := gemini.ValidateResponseFormat(format, candidate.Content); err != nil {
+			if len(candidates) > 1 {
+				return fmt.Errorf("choice %d: %w", candidate.Index, err)
+			}
+			return err
+		}
+	}
+	return nil
 }
 
 func strPtr(s string) *string {
@@ -0,0 +1,694 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gemini-cli-go/internal/config"
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/gemini"
+	"gemini-cli-go/internal/store"
+	"gemini-cli-go/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ResponsesHandler handles OpenAI Responses API requests
+type ResponsesHandler struct {
+	config       *config.Config
+	geminiClient *gemini.Client
+	store        store.ResponseStore
+}
+
+// NewResponsesHandler creates a new Responses API handler backed by the given response store
+func NewResponsesHandler(config *config.Config, geminiClient *gemini.Client, responseStore store.ResponseStore) *ResponsesHandler {
+	return &ResponsesHandler{
+		config:       config,
+		geminiClient: geminiClient,
+		store:        responseStore,
+	}
+}
+
+// CreateResponse handles POST /v1/responses
+func (h *ResponsesHandler) CreateResponse(c *gin.Context) {
+	var req types.ResponsesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, errorTypeInvalidRequest, "invalid_request", err.Error())
+		return
+	}
+
+	if apiErr := validateModel(req.Model); apiErr != nil {
+		writeOpenAIError(c, apiErr.status, apiErr.errorType, apiErr.code, apiErr.message)
+		return
+	}
+
+	if apiErr := checkUnsupportedParams(h.config, unsupportedResponsesParams(req)); apiErr != nil {
+		writeOpenAIError(c, apiErr.status, apiErr.errorType, apiErr.code, apiErr.message)
+		return
+	}
+
+	// Continue the conversation of a stored response
+	var messages []types.ChatMessage
+	if req.PreviousResponseID != "" {
+		previous, ok := h.store.Get(req.PreviousResponseID)
+		if !ok {
+			writeOpenAIError(c, http.StatusNotFound, errorTypeInvalidRequest, "previous_response_not_found",
+				fmt.Sprintf("Previous response with id '%s' not found.", req.PreviousResponseID))
+			return
+		}
+		messages = append(messages, previous.Messages...)
+	}
+
+	input, err := convertResponsesInput(req.Input)
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, errorTypeInvalidRequest, "invalid_request", err.Error())
+		return
+	}
+	messages = append(messages, input...)
+
+	options, err := h.streamOptions(req)
+	if err != nil {
+		writeOpenAIError(c, http.StatusBadRequest, errorTypeInvalidRequest, "invalid_request", err.Error())
+		return
+	}
+
+	options.Unary = !req.Stream
+	chunkChan, err := h.geminiClient.StreamContent(c.Request.Context(), req.Model, req.Instructions, messages, options)
+	if err != nil {
+		writeClientError(c, err)
+		return
+	}
+
+	builder := newResponsesBuilder(req)
+	if req.Stream {
+		builder.emit = func(event string, data interface{}) {
+			if !c.Writer.Written() {
+				c.Writer.Header().Set("Content-Type", constants.ContentTypeSSE)
+				c.Writer.Header().Set("Cache-Control", "no-cache")
+				c.Writer.Header().Set("Connection", "keep-alive")
+			}
+			c.SSEvent(event, data)
+			c.Writer.Flush()
+		}
+		setStreamDeadline(c, h.config.GetStreamMaxDuration())
+		chunkChan = withHeartbeats(c.Request.Context(), chunkChan, h.config.GetSSEHeartbeatInterval())
+	}
+
+	for chunk := range chunkChan {
+		if chunk.Type == types.StreamChunkTypeHeartbeat {
+			writeSSEHeartbeat(c)
+			continue
+		}
+
+		if chunk.Type == types.StreamChunkTypeError {
+			err, ok := chunk.Data.(error)
+			if !ok {
+				err = fmt.Errorf("%v", chunk.Data)
+			}
+			log.Printf("Responses request failed: %v", err)
+
+			// Errors before the first event still get a proper HTTP status
+			if req.Stream && c.Writer.Written() {
+				builder.fail(err)
+			} else {
+				writeClientError(c, err)
+			}
+			return
+		}
+
+		if chunk.Type == types.StreamChunkTypeModel {
+			if model, ok := chunk.Data.(string); ok && !c.Writer.Written() {
+				c.Header(constants.HeaderGeminiFallback, model)
+			}
+		}
+
+		builder.add(chunk)
+	}
+
+	builder.finish()
+
+	// Store before completing, so the next request of a client can chain on this response right away
+	if req.Store == nil || *req.Store {
+		h.store.Put(builder.response.ID, &store.StoredResponse{
+			Response:  builder.response,
+			Messages:  append(messages, builder.historyMessages()...),
+			CreatedAt: time.Now(),
+		})
+	}
+
+	builder.complete()
+	if !req.Stream {
+		c.JSON(http.StatusOK, builder.response)
+	}
+}
+
+// unsupportedResponsesParams returns the Responses API request parameters that have no Gemini equivalent
+func unsupportedResponsesParams(req types.ResponsesRequest) []string {
+	var params []string
+	if req.TopLogprobs != nil {
+		params = append(params, "top_logprobs")
+	}
+	for _, include := range req.Include {
+		if include == constants.ResponsesIncludeLogprobs {
+			params = append(params, "include: "+include)
+		}
+	}
+	return params
+}
+
+// streamOptions builds the Gemini stream options for a Responses API request
+func (h *ResponsesHandler) streamOptions(req types.ResponsesRequest) (*gemini.StreamOptions, error) {
+	tools, err := convertResponsesTools(req.Tools)
+	if err != nil {
+		return nil, err
+	}
+
+	toolChoice, err := convertResponsesToolChoice(req.ToolChoice)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &gemini.StreamOptions{
+		Temperature:        req.Temperature,
+		TopP:               req.TopP,
+		MaxTokens:          req.MaxOutputTokens,
+		EnableRealThinking: req.Reasoning != nil || h.config.IsRealThinkingEnabled(),
+		EnableFakeThinking: h.config.IsFakeThinkingEnabled(),
+		Tools:              tools,
+		ToolChoice:         toolChoice,
+		ParallelToolCalls:  req.ParallelToolCalls,
+	}
+
+	if req.Reasoning != nil {
+		options.ReasoningEffort = req.Reasoning.Effort
+	}
+
+	if req.Text != nil && req.Text.Format != nil {
+		format := req.Text.Format
+		options.ResponseFormat = &types.ResponseFormat{Type: format.Type}
+		if format.Type == gemini.ResponseFormatJSONSchema {
+			options.ResponseFormat.JSONSchema = &types.ResponseFormatJSONSchema{
+				Name:        format.Name,
+				Description: format.Description,
+				Schema:      format.Schema,
+				Strict:      format.Strict,
+			}
+		}
+	}
+
+	return options, nil
+}
+
+// convertResponsesInput converts Responses API input items to chat messages
+func convertResponsesInput(items types.ResponsesInput) ([]types.ChatMessage, error) {
+	var messages []types.ChatMessage
+
+	for i, item := range items {
+		itemType := item.Type
+		if itemType == "" && item.Role != "" {
+			itemType = "message"
+		}
+
+		switch itemType {
+		case "message":
+			switch item.Role {
+			case "user", "assistant", "system", "developer":
+			default:
+				return nil, fmt.Errorf("input[%d]: invalid role: %s", i, item.Role)
+			}
+			content, err := convertResponsesContent(item.Content)
+			if err != nil {
+				return nil, fmt.Errorf("input[%d]: %w", i, err)
+			}
+			messages = append(messages, types.ChatMessage{Role: item.Role, Content: content})
+
+		case "function_call":
+			toolCall := types.ToolCall{
+				ID:   item.CallID,
+				Type: "function",
+				Function: types.ToolCallFunction{
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				},
+			}
+
+			// Calls made in the same turn belong to one assistant message
+			if last := len(messages) - 1; last >= 0 && messages[last].Role == "assistant" {
+				messages[last].ToolCalls = append(messages[last].ToolCalls, toolCall)
+			} else {
+				messages = append(messages, types.ChatMessage{Role: "assistant", ToolCalls: []types.ToolCall{toolCall}})
+			}
+
+		case "function_call_output":
+			messages = append(messages, types.ChatMessage{
+				Role:       "tool",
+				ToolCallID: item.CallID,
+				Content:    item.Output,
+			})
+
+		case "reasoning":
+			// Gemini thoughts cannot be replayed, so earlier reasoning is dropped from the history
+
+		default:
+			return nil, fmt.Errorf("input[%d]: unsupported item type: %s", i, item.Type)
+		}
+	}
+
+	return messages, nil
+}
+
+// convertResponsesContent converts Responses API message content to chat message content
+func convertResponsesContent(content interface{}) (interface{}, error) {
+	items, ok := content.([]interface{})
+	if !ok {
+		return content, nil
+	}
+
+	var parts []interface{}
+	for _, item := range items {
+		part, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		partType, _ := part["type"].(string)
+		switch partType {
+		case "input_text", "output_text", "text":
+			text, _ := part["text"].(string)
+			parts = append(parts, map[string]interface{}{"type": "text", "text": text})
+
+		case "input_image":
+			url, _ := part["image_url"].(string)
+			if url == "" {
+				return nil, fmt.Errorf("input_image requires an image_url")
+			}
+			parts = append(parts, map[string]interface{}{
+				"type":      "image_url",
+				"image_url": map[string]interface{}{"url": url},
+			})
+
+		case "refusal":
+			// Refusals carry no content worth sending back
+
+		default:
+			return nil, fmt.Errorf("unsupported content type: %s", partType)
+		}
+	}
+
+	return parts, nil
+}
+
+// convertResponsesTools converts Responses API function tools to OpenAI tool definitions
+func convertResponsesTools(tools []types.ResponsesTool) ([]types.Tool, error) {
+	var converted []types.Tool
+	for _, tool := range tools {
+		if tool.Type != "function" {
+			return nil, fmt.Errorf("unsupported tool type: %s", tool.Type)
+		}
+		converted = append(converted, types.Tool{
+			Type: "function",
+			Function: &types.ToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+				Strict:      tool.Strict,
+			},
+		})
+	}
+	return converted, nil
+}
+
+// convertResponsesToolChoice converts a Responses API tool_choice to a chat completions tool_choice
+func convertResponsesToolChoice(toolChoice interface{}) (interface{}, error) {
+	choice, ok := toolChoice.(map[string]interface{})
+	if !ok {
+		return toolChoice, nil
+	}
+
+	if choiceType, _ := choice["type"].(string); choiceType == "function" {
+		if name, ok := choice["name"].(string); ok && name != "" {
+			return map[string]interface{}{
+				"type":     "function",
+				"function": map[string]interface{}{"name": name},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid tool_choice: %v", toolChoice)
+}
+
+// responsesBuilder assembles a Responses API response from Gemini stream chunks,
+// emitting the typed streaming events when streaming
+type responsesBuilder struct {
+	response     types.ResponsesResponse
+	itemOpen     bool
+	finishReason string
+	sequence     int
+	started      bool
+	emit         func(event string, data interface{})
+}
+
+// newResponsesBuilder creates a builder for a response to req
+func newResponsesBuilder(req types.ResponsesRequest) *responsesBuilder {
+	response := types.ResponsesResponse{
+		ID:                constants.ResponseIDPrefix + newItemID(),
+		Object:            "response",
+		CreatedAt:         time.Now().Unix(),
+		Status:            "in_progress",
+		Model:             req.Model,
+		Output:            []types.ResponsesItem{},
+		Tools:             req.Tools,
+		ToolChoice:        req.ToolChoice,
+		ParallelToolCalls: req.ParallelToolCalls == nil || *req.ParallelToolCalls,
+		Temperature:       req.Temperature,
+		TopP:              req.TopP,
+		MaxOutputTokens:   req.MaxOutputTokens,
+		Metadata:          req.Metadata,
+	}
+
+	if response.Tools == nil {
+		response.Tools = []types.ResponsesTool{}
+	}
+	if response.ToolChoice == nil {
+		response.ToolChoice = "auto"
+	}
+	if response.Metadata == nil {
+		response.Metadata = map[string]interface{}{}
+	}
+	if req.Instructions != "" {
+		response.Instructions = strPtr(req.Instructions)
+	}
+	if req.PreviousResponseID != "" {
+		response.PreviousResponseID = strPtr(req.PreviousResponseID)
+	}
+
+	return &responsesBuilder{response: response}
+}
+
+// newItemID returns a random identifier for responses and output items
+func newItemID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")
+}
+
+// add applies a stream chunk to the response
+func (b *responsesBuilder) add(chunk types.StreamChunk) {
+	switch chunk.Type {
+	case types.StreamChunkTypeModel:
+		if model, ok := chunk.Data.(string); ok {
+			b.response.Model = model
+		}
+	case types.StreamChunkTypeText:
+		if text, ok := chunk.Data.(string); ok {
+			b.appendText("message", text)
+		}
+	case types.StreamChunkTypeRealThinking:
+		if thinking, ok := chunk.Data.(string); ok {
+			b.appendText("reasoning", thinking)
+		}
+	case types.StreamChunkTypeReasoning:
+		if reasoning, ok := chunk.Data.(types.ReasoningData); ok {
+			b.appendText("reasoning", reasoning.Reasoning)
+		}
+	case types.StreamChunkTypeToolCall:
+		if toolCall, ok := chunk.Data.(types.ToolCall); ok {
+			b.addFunctionCall(toolCall)
+		}
+	case types.StreamChunkTypeFinishReason:
+		if finish, ok := chunk.Data.(types.FinishData); ok {
+			b.finishReason = finish.FinishReason
+		}
+	case types.StreamChunkTypeUsage:
+		if usage, ok := chunk.Data.(types.UsageData); ok {
+			b.response.Usage = &types.ResponsesUsage{
+				InputTokens:         usage.InputTokens,
+				InputTokensDetails:  types.ResponsesInputTokensDetail{CachedTokens: usage.CachedTokens},
+				OutputTokens:        usage.OutputTokens,
+				OutputTokensDetails: types.ResponsesOutputTokensDetail{ReasoningTokens: usage.ReasoningTokens},
+				TotalTokens:         usage.InputTokens + usage.OutputTokens,
+			}
+		}
+	}
+}
+
+// appendText appends text to the open message or reasoning item, starting a new item when the type changes
+func (b *responsesBuilder) appendText(itemType string, text string) {
+	if text == "" {
+		return
+	}
+
+	index := len(b.response.Output) - 1
+	if !b.itemOpen || b.response.Output[index].Type != itemType {
+		b.closeItem()
+		index = b.openTextItem(itemType)
+	}
+
+	item := &b.response.Output[index]
+	if itemType == "reasoning" {
+		item.Summary[0].Text += text
+		b.send("response.reasoning_summary_text.delta", gin.H{
+			"item_id":       item.ID,
+			"output_index":  index,
+			"summary_index": 0,
+			"delta":         text,
+		})
+		return
+	}
+
+	item.Content.([]types.ResponsesContentPart)[0].Text += text
+	b.send("response.output_text.delta", gin.H{
+		"item_id":       item.ID,
+		"output_index":  index,
+		"content_index": 0,
+		"delta":         text,
+	})
+}
+
+// openTextItem adds a message or reasoning item with a single empty part and returns its index
+func (b *responsesBuilder) openTextItem(itemType string) int {
+	index := len(b.response.Output)
+	b.itemOpen = true
+
+	if itemType == "reasoning" {
+		item := types.ResponsesItem{Type: "reasoning", ID: "rs_" + newItemID()}
+		b.send("response.output_item.added", gin.H{
+			"output_index": index,
+			"item":         gin.H{"type": "reasoning", "id": item.ID, "summary": []interface{}{}},
+		})
+
+		part := types.ResponsesSummaryPart{Type: "summary_text"}
+		item.Summary = []types.ResponsesSummaryPart{part}
+		b.response.Output = append(b.response.Output, item)
+		b.send("response.reasoning_summary_part.added", gin.H{
+			"item_id":       item.ID,
+			"output_index":  index,
+			"summary_index": 0,
+			"part":          part,
+		})
+		return index
+	}
+
+	item := types.ResponsesItem{
+		Type:    "message",
+		ID:      "msg_" + newItemID(),
+		Status:  "in_progress",
+		Role:    "assistant",
+		Content: []types.ResponsesContentPart{},
+	}
+	b.send("response.output_item.added", gin.H{
+		"output_index": index,
+		"item":         item,
+	})
+
+	part := types.ResponsesContentPart{Type: "output_text", Annotations: []interface{}{}}
+	item.Content = []types.ResponsesContentPart{part}
+	b.response.Output = append(b.response.Output, item)
+	b.send("response.content_part.added", gin.H{
+		"item_id":       item.ID,
+		"output_index":  index,
+		"content_index": 0,
+		"part":          part,
+	})
+	return index
+}
+
+// addFunctionCall adds a complete function_call item
+func (b *responsesBuilder) addFunctionCall(toolCall types.ToolCall) {
+	b.closeItem()
+
+	index := len(b.response.Output)
+	item := types.ResponsesItem{
+		Type:      "function_call",
+		ID:        "fc_" + newItemID(),
+		Status:    "completed",
+		CallID:    toolCall.ID,
+		Name:      toolCall.Function.Name,
+		Arguments: toolCall.Function.Arguments,
+	}
+	b.response.Output = append(b.response.Output, item)
+
+	b.send("response.output_item.added", gin.H{
+		"output_index": index,
+		"item": gin.H{
+			"type":      "function_call",
+			"id":        item.ID,
+			"status":    "in_progress",
+			"call_id":   item.CallID,
+			"name":      item.Name,
+			"arguments": "",
+		},
+	})
+	b.send("response.function_call_arguments.delta", gin.H{
+		"item_id":      item.ID,
+		"output_index": index,
+		"delta":        item.Arguments,
+	})
+	b.send("response.function_call_arguments.done", gin.H{
+		"item_id":      item.ID,
+		"output_index": index,
+		"arguments":    item.Arguments,
+	})
+	b.send("response.output_item.done", gin.H{
+		"output_index": index,
+		"item":         item,
+	})
+}
+
+// closeItem completes the open message or reasoning item, if any
+func (b *responsesBuilder) closeItem() {
+	if !b.itemOpen {
+		return
+	}
+	b.itemOpen = false
+
+	index := len(b.response.Output) - 1
+	item := &b.response.Output[index]
+
+	if item.Type == "reasoning" {
+		part := item.Summary[0]
+		b.send("response.reasoning_summary_text.done", gin.H{
+			"item_id":       item.ID,
+			"output_index":  index,
+			"summary_index": 0,
+			"text":          part.Text,
+		})
+		b.send("response.reasoning_summary_part.done", gin.H{
+			"item_id":       item.ID,
+			"output_index":  index,
+			"summary_index": 0,
+			"part":          part,
+		})
+	} else {
+		part := item.Content.([]types.ResponsesContentPart)[0]
+		b.send("response.output_text.done", gin.H{
+			"item_id":       item.ID,
+			"output_index":  index,
+			"content_index": 0,
+			"text":          part.Text,
+		})
+		b.send("response.content_part.done", gin.H{
+			"item_id":       item.ID,
+			"output_index":  index,
+			"content_index": 0,
+			"part":          part,
+		})
+		item.Status = "completed"
+	}
+
+	b.send("response.output_item.done", gin.H{
+		"output_index": index,
+		"item":         *item,
+	})
+}
+
+// finish closes the output and sets the final status of the response
+func (b *responsesBuilder) finish() {
+	b.closeItem()
+
+	switch b.finishReason {
+	case constants.FinishReasonLength:
+		b.response.Status = "incomplete"
+		b.response.IncompleteDetails = &types.ResponsesIncompleteDetail{Reason: "max_output_tokens"}
+	case constants.FinishReasonContentFilter:
+		b.response.Status = "incomplete"
+		b.response.IncompleteDetails = &types.ResponsesIncompleteDetail{Reason: "content_filter"}
+	default:
+		b.response.Status = "completed"
+	}
+}
+
+// complete emits the final response event
+func (b *responsesBuilder) complete() {
+	event := "response.completed"
+	if b.response.Status == "incomplete" {
+		event = "response.incomplete"
+	}
+	b.send(event, gin.H{"response": b.response})
+}
+
+// fail emits response.failed for an error that occurred once streaming had started
+func (b *responsesBuilder) fail(err error) {
+	apiErr := classifyError(err)
+	b.response.Status = "failed"
+	b.response.Error = &types.ResponsesError{Code: apiErr.code, Message: apiErr.message}
+	b.send("response.failed", gin.H{"response": b.response})
+}
+
+// send emits a typed SSE event when streaming, preceded by response.created on the first event
+func (b *responsesBuilder) send(event string, data gin.H) {
+	if b.emit == nil {
+		return
+	}
+
+	if !b.started {
+		b.started = true
+		created := b.response
+		created.Output = []types.ResponsesItem{}
+		b.emitEvent("response.created", gin.H{"response": created})
+		b.emitEvent("response.in_progress", gin.H{"response": created})
+	}
+
+	b.emitEvent(event, data)
+}
+
+// emitEvent emits a single event with its type and sequence number
+func (b *responsesBuilder) emitEvent(event string, data gin.H) {
+	data["type"] = event
+	data["sequence_number"] = b.sequence
+	b.sequence++
+	b.emit(event, data)
+}
+
+// historyMessages returns the response output as chat messages for previous_response_id chaining
+func (b *responsesBuilder) historyMessages() []types.ChatMessage {
+	message := types.ChatMessage{Role: "assistant"}
+
+	var texts []string
+	for _, item := range b.response.Output {
+		switch item.Type {
+		case "message":
+			for _, part := range item.Content.([]types.ResponsesContentPart) {
+				texts = append(texts, part.Text)
+			}
+		case "function_call":
+			message.ToolCalls = append(message.ToolCalls, types.ToolCall{
+				ID:   item.CallID,
+				Type: "function",
+				Function: types.ToolCallFunction{
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				},
+			})
+		}
+	}
+
+	if len(texts) == 0 && len(message.ToolCalls) == 0 {
+		return nil
+	}
+	if len(texts) > 0 {
+		message.Content = strings.Join(texts, "")
+	}
+	return []types.ChatMessage{message}
+}
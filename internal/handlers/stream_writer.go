@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ginResponseWriter adapts a gin.ResponseWriter to stream.ResponseWriter
+type ginResponseWriter struct {
+	writer gin.ResponseWriter
+}
+
+// newGinResponseWriter wraps the response writer of a gin context
+func newGinResponseWriter(c *gin.Context) *ginResponseWriter {
+	return &ginResponseWriter{writer: c.Writer}
+}
+
+// Write writes data to the response
+func (w *ginResponseWriter) Write(data []byte) (int, error) {
+	return w.writer.Write(data)
+}
+
+// Flush sends buffered data to the client
+func (w *ginResponseWriter) Flush() {
+	w.writer.Flush()
+}
+
+// Header returns the response headers, using the first value of each
+func (w *ginResponseWriter) Header() map[string]string {
+	headers := make(map[string]string, len(w.writer.Header()))
+	for key := range w.writer.Header() {
+		headers[key] = w.writer.Header().Get(key)
+	}
+	return headers
+}
+
+// SetHeader sets a response header
+func (w *ginResponseWriter) SetHeader(key, value string) {
+	w.writer.Header().Set(key, value)
+}
+
+// WriteHeader sets the response status code
+func (w *ginResponseWriter) WriteHeader(statusCode int) {
+	w.writer.WriteHeader(statusCode)
+}
+
+// withHeartbeats relays chunks, adding a heartbeat chunk whenever the upstream stays silent
+// for the interval. A heartbeat commits the response status, so they only start after the
+// first data chunk and errors before it still get a proper HTTP status. A non-positive
+// interval disables heartbeats.
+func withHeartbeats(ctx context.Context, chunkChan <-chan types.StreamChunk, interval time.Duration) <-chan types.StreamChunk {
+	if interval <= 0 {
+		return chunkChan
+	}
+
+	out := make(chan types.StreamChunk)
+	go func() {
+		defer close(out)
+
+		// heartbeats stays nil, blocking forever, until the timer is started
+		var timer *time.Timer
+		var heartbeats <-chan time.Time
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		for {
+			var chunk types.StreamChunk
+			select {
+			case next, ok := <-chunkChan:
+				if !ok {
+					return
+				}
+				chunk = next
+			case <-heartbeats:
+				chunk = types.StreamChunk{Type: types.StreamChunkTypeHeartbeat}
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+
+			// The fallback model notice is not written to the client
+			if chunk.Type == types.StreamChunkTypeModel {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(interval)
+				heartbeats = timer.C
+				continue
+			}
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(interval)
+		}
+	}()
+
+	return out
+}
+
+// writeSSEHeartbeat writes an SSE comment, which clients ignore, to keep an idle connection open
+func writeSSEHeartbeat(c *gin.Context) {
+	if !c.Writer.Written() {
+		c.Writer.Header().Set("Content-Type", constants.ContentTypeSSE)
+		c.Writer.Header().Set("Cache-Control", "no-cache")
+		c.Writer.Header().Set("Connection", "keep-alive")
+	}
+	fmt.Fprintf(c.Writer, "%s\n\n", constants.SSEHeartbeatMessage)
+	c.Writer.Flush()
+}
+
+// setStreamDeadline replaces the server timeouts, which are the hard limit of non-streaming
+// requests, for a streaming response: it may be written until the maximum stream duration passes
+func setStreamDeadline(c *gin.Context, maxDuration time.Duration) {
+	var deadline time.Time
+	if maxDuration > 0 {
+		deadline = time.Now().Add(maxDuration)
+	}
+
+	controller := http.NewResponseController(c.Writer)
+	if err := controller.SetWriteDeadline(deadline); err != nil {
+		log.Printf("Failed to set stream write deadline: %v", err)
+	}
+	// The server cancels the request context once the read deadline passes, so it follows the write deadline
+	if err := controller.SetReadDeadline(deadline); err != nil {
+		log.Printf("Failed to set stream read deadline: %v", err)
+	}
+}
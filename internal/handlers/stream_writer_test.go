@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gemini-cli-go/internal/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// collectChunkTypes returns the types of all chunks relayed by withHeartbeats
+func collectChunkTypes(chunkChan <-chan types.StreamChunk, interval time.Duration) []types.StreamChunkType {
+	var chunkTypes []types.StreamChunkType
+	for chunk := range withHeartbeats(context.Background(), chunkChan, interval) {
+		chunkTypes = append(chunkTypes, chunk.Type)
+	}
+	return chunkTypes
+}
+
+func TestWithHeartbeats_NoneBeforeFirstChunk(t *testing.T) {
+	chunkChan := make(chan types.StreamChunk)
+	go func() {
+		defer close(chunkChan)
+		chunkChan <- types.StreamChunk{Type: types.StreamChunkTypeModel, Data: "gemini-2.5-flash"}
+		time.Sleep(50 * time.Millisecond)
+		chunkChan <- types.StreamChunk{Type: types.StreamChunkTypeError, Data: errors.New("rate limited")}
+	}()
+
+	// A slow first chunk must not commit the response before an error can set its status
+	assert.Equal(t, []types.StreamChunkType{types.StreamChunkTypeModel, types.StreamChunkTypeError}, collectChunkTypes(chunkChan, 10*time.Millisecond))
+}
+
+func TestWithHeartbeats_AfterFirstChunk(t *testing.T) {
+	chunkChan := make(chan types.StreamChunk)
+	go func() {
+		defer close(chunkChan)
+		chunkChan <- types.StreamChunk{Type: types.StreamChunkTypeText, Data: "Hi"}
+		time.Sleep(50 * time.Millisecond)
+		chunkChan <- types.StreamChunk{Type: types.StreamChunkTypeText, Data: "!"}
+	}()
+
+	chunkTypes := collectChunkTypes(chunkChan, 10*time.Millisecond)
+	assert.Equal(t, types.StreamChunkTypeText, chunkTypes[0])
+	assert.Contains(t, chunkTypes[1:len(chunkTypes)-1], types.StreamChunkTypeHeartbeat)
+	assert.Equal(t, types.StreamChunkTypeText, chunkTypes[len(chunkTypes)-1])
+}
+
+func TestWithHeartbeats_Disabled(t *testing.T) {
+	chunkChan := make(chan types.StreamChunk)
+	assert.Equal(t, (<-chan types.StreamChunk)(chunkChan), withHeartbeats(context.Background(), chunkChan, 0))
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/stream"
+	"gemini-cli-go/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// ChatWebSocket handles GET /v1/chat/ws, streaming chat completions over a single WebSocket
+func (h *OpenAIHandler) ChatWebSocket(c *gin.Context) {
+	upgrader := websocket.Upgrader{
+		CheckOrigin:  h.checkWebSocketOrigin,
+		Subprotocols: []string{constants.WebSocketProtocol},
+	}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already answered with an HTTP error
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &chatSession{
+		handler: h,
+		conn:    conn,
+		ctx:     ctx,
+		cancels: make(map[string]context.CancelFunc),
+	}
+	session.run()
+
+	// Completions end with the connection
+	cancel()
+	session.wg.Wait()
+}
+
+// checkWebSocketOrigin accepts clients without an Origin header, which are not browsers, and browsers
+// on the server's own host or an origin allowed by WEBSOCKET_ALLOWED_ORIGINS. Browsers send cookies and
+// cached credentials with a cross-site WebSocket, so other sites must not open one.
+func (h *OpenAIHandler) checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range h.config.GetWebSocketAllowedOrigins() {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	log.Printf("Rejected WebSocket connection from origin %s", origin)
+	return false
+}
+
+// chatSession serves the completions of one WebSocket connection, which run concurrently
+type chatSession struct {
+	handler *OpenAIHandler
+	conn    *websocket.Conn
+	ctx     context.Context
+
+	// writeMu serializes writes, the connection supports a single writer at a time
+	writeMu sync.Mutex
+
+	// cancels holds the cancel functions of the running completions by client id
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// run reads messages until the connection is closed. Pings are sent every heartbeat interval,
+// and a client that stops answering them is disconnected.
+func (s *chatSession) run() {
+	interval := s.handler.config.GetSSEHeartbeatInterval()
+
+	// The hijacked connection still carries the deadlines of the HTTP server
+	extendReadDeadline := func() {
+		var deadline time.Time
+		if interval > 0 {
+			deadline = time.Now().Add(2 * interval)
+		}
+		s.conn.SetReadDeadline(deadline)
+	}
+	extendReadDeadline()
+	s.conn.SetPongHandler(func(string) error {
+		extendReadDeadline()
+		return nil
+	})
+
+	if interval > 0 {
+		go s.ping(interval)
+	}
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("WebSocket connection closed: %v", err)
+			}
+			return
+		}
+		extendReadDeadline()
+		s.handleMessage(data)
+	}
+}
+
+// ping sends a ping every interval until the session ends
+func (s *chatSession) ping(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(constants.WebSocketWriteTimeout)); err != nil {
+				log.Printf("Failed to send WebSocket ping: %v", err)
+				return
+			}
+		}
+	}
+}
+
+// handleMessage starts or cancels a completion
+func (s *chatSession) handleMessage(data []byte) {
+	var msg types.ChatWebSocketMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		s.sendError("", newInvalidRequestError("invalid_request", err.Error()))
+		return
+	}
+	if msg.ID == "" {
+		s.sendError("", newInvalidRequestError("invalid_request", "id is required"))
+		return
+	}
+
+	switch msg.Type {
+	case "":
+		var req types.ChatCompletionRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			s.sendError(msg.ID, newInvalidRequestError("invalid_request", err.Error()))
+			return
+		}
+		if apiErr := s.handler.validateChatRequest(req); apiErr != nil {
+			s.sendError(msg.ID, apiErr)
+			return
+		}
+		s.start(msg.ID, req)
+
+	case constants.WebSocketMessageCancel:
+		// Completions that already ended have nothing left to cancel
+		s.mu.Lock()
+		if cancel, ok := s.cancels[msg.ID]; ok {
+			cancel()
+		}
+		s.mu.Unlock()
+
+	default:
+		s.sendError(msg.ID, newInvalidRequestError("invalid_request", fmt.Sprintf("unsupported message type: %s", msg.Type)))
+	}
+}
+
+// start runs a completion in the background, unless one with the same id is still running
+// or the connection already runs as many completions as it may
+func (s *chatSession) start(id string, req types.ChatCompletionRequest) {
+	s.mu.Lock()
+	if _, exists := s.cancels[id]; exists {
+		s.mu.Unlock()
+		s.sendError(id, newInvalidRequestError("duplicate_id", fmt.Sprintf("a completion with id %s is already running", id)))
+		return
+	}
+	if limit := s.handler.config.GetWebSocketMaxConcurrent(); limit > 0 && len(s.cancels) >= limit {
+		s.mu.Unlock()
+		s.sendError(id, &apiError{
+			status:    http.StatusTooManyRequests,
+			errorType: errorTypeRateLimit,
+			code:      "too_many_concurrent_requests",
+			message:   fmt.Sprintf("at most %d completions may run at once on a connection", limit),
+		})
+		return
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.cancels[id] = cancel
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.cancels, id)
+			s.mu.Unlock()
+			cancel()
+		}()
+
+		s.complete(ctx, id, req)
+	}()
+}
+
+// complete streams a completion as chunk messages, ending with a done, cancelled or error message
+func (s *chatSession) complete(ctx context.Context, id string, req types.ChatCompletionRequest) {
+	chunkChan, err := s.handler.geminiClient.StreamContent(ctx, req.Model, "", req.Messages, s.handler.streamOptions(req))
+	if err != nil {
+		apiErr := classifyError(err)
+		s.sendError(id, &apiErr)
+		return
+	}
+
+	transformer := stream.NewTransformer(req.Model)
+	transformer.SetReasoningFormat(s.handler.reasoningFormat(req))
+	streamed := newStreamedCandidates(req.ResponseFormat)
+
+	for chunk := range chunkChan {
+		switch chunk.Type {
+		case types.StreamChunkTypeError:
+			// A cancelled completion ends with a cancelled message instead
+			if ctx.Err() != nil {
+				continue
+			}
+			err, ok := chunk.Data.(error)
+			if !ok {
+				err = fmt.Errorf("%v", chunk.Data)
+			}
+			log.Printf("WebSocket completion %s failed: %v", id, err)
+			apiErr := classifyError(err)
+			s.sendError(id, &apiErr)
+			return
+
+		case types.StreamChunkTypeModel:
+			if model, ok := chunk.Data.(string); ok {
+				transformer.SetModel(model)
+			}
+
+		default:
+			streamed.add(chunk)
+			responses, err := transformer.TransformChunks(chunk)
+			if err != nil {
+				log.Printf("Failed to transform chunk: %v", err)
+				continue
+			}
+			for _, response := range responses {
+				s.send(types.ChatWebSocketResponse{Type: constants.WebSocketMessageChunk, ID: id, Chunk: response})
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		s.send(types.ChatWebSocketResponse{Type: constants.WebSocketMessageCancelled, ID: id})
+		return
+	}
+
+	if err := streamed.validate(); err != nil {
+		s.sendError(id, responseFormatMismatchError(err))
+		return
+	}
+
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		if usage := transformer.UsageChunk(); usage != nil {
+			s.send(types.ChatWebSocketResponse{Type: constants.WebSocketMessageChunk, ID: id, Chunk: usage})
+		}
+	}
+	s.send(types.ChatWebSocketResponse{Type: constants.WebSocketMessageDone, ID: id})
+}
+
+// sendError sends an error message for the completion with the id
+func (s *chatSession) sendError(id string, apiErr *apiError) {
+	errorResponse := newOpenAIErrorResponse(apiErr.errorType, apiErr.code, apiErr.message)
+	s.send(types.ChatWebSocketResponse{Type: constants.WebSocketMessageError, ID: id, Error: &errorResponse.Error})
+}
+
+// send writes a message to the connection. Like the SSE stream, HTML characters are not escaped.
+func (s *chatSession) send(message types.ChatWebSocketResponse) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.conn.SetWriteDeadline(time.Now().Add(constants.WebSocketWriteTimeout))
+	writer, err := s.conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		log.Printf("Failed to write WebSocket message: %v", err)
+		return
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(message); err != nil {
+		log.Printf("Failed to write WebSocket message: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Failed to write WebSocket message: %v", err)
+	}
+}
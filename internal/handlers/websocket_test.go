@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gemini-cli-go/internal/constants"
+	"gemini-cli-go/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveChatWebSocket serves the WebSocket endpoint of handler and returns its URL
+func serveChatWebSocket(t *testing.T, handler *OpenAIHandler) string {
+	engine := gin.New()
+	engine.GET("/v1/chat/ws", handler.ChatWebSocket)
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/v1/chat/ws"
+}
+
+// dialChatWebSocket opens a connection with the given request headers
+func dialChatWebSocket(t *testing.T, url string, header http.Header) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readMessage reads the next server message, failing the test after a second
+func readMessage(t *testing.T, conn *websocket.Conn) types.ChatWebSocketResponse {
+	t.Helper()
+	var message types.ChatWebSocketResponse
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	require.NoError(t, conn.ReadJSON(&message))
+	return message
+}
+
+func TestChatWebSocket_Completion(t *testing.T) {
+	handler, _ := setupTestHandler()
+	serveFakeUpstream(t, handler.config, "Hi!")
+	conn := dialChatWebSocket(t, serveChatWebSocket(t, handler), nil)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage,
+		[]byte(`{"id":"req-1","model":"gemini-2.5-flash","messages":[{"role":"user","content":"Hello"}]}`)))
+
+	var content strings.Builder
+	for {
+		message := readMessage(t, conn)
+		assert.Equal(t, "req-1", message.ID)
+		if message.Type != constants.WebSocketMessageChunk {
+			assert.Equal(t, constants.WebSocketMessageDone, message.Type)
+			break
+		}
+		for _, choice := range message.Chunk.Choices {
+			if choice.Delta != nil {
+				content.WriteString(choice.Delta.Content)
+			}
+		}
+	}
+	assert.Equal(t, "Hi!", content.String())
+}
+
+func TestChatWebSocket_InvalidMessages(t *testing.T) {
+	handler, _ := setupTestHandler()
+	conn := dialChatWebSocket(t, serveChatWebSocket(t, handler), nil)
+
+	tests := []struct {
+		message string
+		id      string
+		code    string
+	}{
+		{message: `not json`, code: "invalid_request"},
+		{message: `{"model":"gemini-2.5-flash"}`, code: "invalid_request"},
+		{message: `{"id":"a","model":"unknown","messages":[{"role":"user","content":"Hi"}]}`, id: "a", code: "model_not_found"},
+		{message: `{"id":"b","type":"unknown"}`, id: "b", code: "invalid_request"},
+	}
+
+	for _, tt := range tests {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(tt.message)))
+		message := readMessage(t, conn)
+		assert.Equal(t, constants.WebSocketMessageError, message.Type, tt.message)
+		assert.Equal(t, tt.id, message.ID, tt.message)
+		if assert.NotNil(t, message.Error, tt.message) && assert.NotNil(t, message.Error.Code, tt.message) {
+			assert.Equal(t, tt.code, *message.Error.Code, tt.message)
+		}
+	}
+}
+
+func TestChatWebSocket_ConcurrencyLimit(t *testing.T) {
+	handler, _ := setupTestHandler()
+	release := make(chan struct{})
+	serveUpstream(t, handler.config, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	t.Cleanup(func() { close(release) })
+
+	handler.config.Environment.WebSocketMaxConcurrent = 1
+	conn := dialChatWebSocket(t, serveChatWebSocket(t, handler), nil)
+
+	request := `{"id":"%s","model":"gemini-2.5-flash","messages":[{"role":"user","content":"Hello"}]}`
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(request, "first"))))
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(request, "second"))))
+
+	message := readMessage(t, conn)
+	assert.Equal(t, constants.WebSocketMessageError, message.Type)
+	assert.Equal(t, "second", message.ID)
+	if assert.NotNil(t, message.Error) && assert.NotNil(t, message.Error.Code) {
+		assert.Equal(t, "too_many_concurrent_requests", *message.Error.Code)
+	}
+
+	// Cancelling the first completion frees its slot
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"id":"first","type":"cancel"}`)))
+	message = readMessage(t, conn)
+	assert.Equal(t, constants.WebSocketMessageCancelled, message.Type)
+	assert.Equal(t, "first", message.ID)
+}
+
+func TestChatWebSocket_Origin(t *testing.T) {
+	handler, _ := setupTestHandler()
+	handler.config.Environment.WebSocketAllowedOrigins = "https://chat.example.com"
+	url := serveChatWebSocket(t, handler)
+	host := strings.TrimPrefix(url, "ws://")
+	host = host[:strings.Index(host, "/")]
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "no origin", allowed: true},
+		{name: "same host", origin: "http://" + host, allowed: true},
+		{name: "allowed origin", origin: "https://chat.example.com", allowed: true},
+		{name: "other origin", origin: "https://evil.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.origin != "" {
+				header.Set("Origin", tt.origin)
+			}
+			conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+			if !tt.allowed {
+				require.Error(t, err)
+				assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+				return
+			}
+			require.NoError(t, err)
+			conn.Close()
+		})
+	}
+}
+
+func TestChatWebSocket_Subprotocol(t *testing.T) {
+	handler, _ := setupTestHandler()
+	dialer := websocket.Dialer{Subprotocols: []string{constants.WebSocketProtocol, constants.WebSocketAPIKeyPrefix + "sk-test"}}
+
+	conn, _, err := dialer.Dial(serveChatWebSocket(t, handler), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, constants.WebSocketProtocol, conn.Subprotocol())
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"sync"
+)
+
+// Counter names
+const (
+	UpstreamRequests         = "upstream_requests_total"
+	UpstreamRetries          = "upstream_retries_total"
+	UpstreamRetriesExhausted = "upstream_retries_exhausted_total"
+	AccountFailovers         = "account_failovers_total"
+	ModelFallbacks           = "model_fallbacks_total"
+)
+
+// Registry holds named counters
+type Registry struct {
+	mu       sync.Mutex
+	counters map[string]int64
+}
+
+// NewRegistry creates an empty counter registry
+func NewRegistry() *Registry {
+	return &Registry{
+		counters: make(map[string]int64),
+	}
+}
+
+// Add adds delta to the named counter
+func (r *Registry) Add(name string, delta int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counters[name] += delta
+}
+
+// Inc increments the named counter
+func (r *Registry) Inc(name string) {
+	r.Add(name, 1)
+}
+
+// Snapshot returns a copy of all counters
+func (r *Registry) Snapshot() map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make(map[string]int64, len(r.counters))
+	for name, value := range r.counters {
+		snapshot[name] = value
+	}
+	return snapshot
+}
+
+// defaultRegistry is the process-wide registry
+var defaultRegistry = NewRegistry()
+
+// Add adds delta to a counter in the default registry
+func Add(name string, delta int64) {
+	defaultRegistry.Add(name, delta)
+}
+
+// Inc increments a counter in the default registry
+func Inc(name string) {
+	defaultRegistry.Inc(name)
+}
+
+// Snapshot returns a copy of the counters in the default registry
+func Snapshot() map[string]int64 {
+	return defaultRegistry.Snapshot()
+}
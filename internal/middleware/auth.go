@@ -20,12 +20,17 @@ func AuthMiddleware(apiKey string) gin.HandlerFunc {
 			return
 		}
 
+		// Anthropic and Google clients send the key in their own headers instead
+		if authenticateAlternateAPIKey(c, apiKey) {
+			return
+		}
+
 		// Get authorization header
 		authHeader := c.GetHeader(constants.AuthorizationHeader)
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, types.ErrorResponse{
 				Error:   "Missing authorization header",
-				Message: "Please provide the Authorization header with your API key",
+				Message: "Please provide the Authorization, x-api-key or x-goog-api-key header with your API key",
 				Code:    http.StatusUnauthorized,
 			})
 			c.Abort()
@@ -80,6 +85,11 @@ func OptionalAuthMiddleware(apiKey string) gin.HandlerFunc {
 			return
 		}
 
+		// Anthropic and Google clients send the key in their own headers instead
+		if authenticateAlternateAPIKey(c, apiKey) {
+			return
+		}
+
 		// Get authorization header
 		authHeader := c.GetHeader(constants.AuthorizationHeader)
 		if authHeader == "" {
@@ -116,6 +126,73 @@ func OptionalAuthMiddleware(apiKey string) gin.HandlerFunc {
 	}
 }
 
+// queryAPIKeyAllowed is the context key set by QueryAPIKeyMiddleware
+const queryAPIKeyAllowed = "query_api_key_allowed"
+
+// QueryAPIKeyMiddleware lets the routes of a group send the key query parameter, as the
+// Google GenAI SDKs do. It must be added before the authentication middleware.
+func QueryAPIKeyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(queryAPIKeyAllowed, true)
+		c.Next()
+	}
+}
+
+// authenticateAlternateAPIKey checks the key sent without an Authorization header, continuing or
+// aborting the request, and reports whether such a key was sent
+func authenticateAlternateAPIKey(c *gin.Context, apiKey string) bool {
+	key := alternateAPIKey(c)
+	if key == "" {
+		return false
+	}
+
+	if key != apiKey {
+		c.JSON(http.StatusUnauthorized, types.ErrorResponse{
+			Error:   "Invalid API key",
+			Message: "The provided API key is invalid",
+			Code:    http.StatusUnauthorized,
+		})
+		c.Abort()
+		return true
+	}
+
+	c.Set("authenticated", true)
+	c.Next()
+	return true
+}
+
+// alternateAPIKey returns the key sent in the x-api-key or x-goog-api-key header, the
+// WebSocket subprotocol, or the key query parameter where allowed, when no Authorization
+// header was sent
+func alternateAPIKey(c *gin.Context) string {
+	if c.GetHeader(constants.AuthorizationHeader) != "" {
+		return ""
+	}
+	if key := c.GetHeader(constants.APIKeyHeader); key != "" {
+		return key
+	}
+	if key := c.GetHeader(constants.GoogleAPIKeyHeader); key != "" {
+		return key
+	}
+	if key := webSocketAPIKey(c); key != "" {
+		return key
+	}
+	if c.GetBool(queryAPIKeyAllowed) {
+		return c.Query(constants.APIKeyQueryParam)
+	}
+	return ""
+}
+
+// webSocketAPIKey returns the key offered as a WebSocket subprotocol by browser clients
+func webSocketAPIKey(c *gin.Context) string {
+	for _, protocol := range strings.Split(c.GetHeader(constants.WebSocketProtocolHeader), ",") {
+		if key, ok := strings.CutPrefix(strings.TrimSpace(protocol), constants.WebSocketAPIKeyPrefix); ok {
+			return key
+		}
+	}
+	return ""
+}
+
 // IsAuthenticated checks if the request is authenticated
 func IsAuthenticated(c *gin.Context) bool {
 	authenticated, exists := c.Get("authenticated")
@@ -134,6 +211,11 @@ func RequireAuthMiddleware(apiKey string) gin.HandlerFunc {
 			return
 		}
 
+		// Anthropic and Google clients send the key in their own headers instead
+		if authenticateAlternateAPIKey(c, apiKey) {
+			return
+		}
+
 		// Get authorization header
 		authHeader := c.GetHeader(constants.AuthorizationHeader)
 		if authHeader == "" {
@@ -215,7 +297,7 @@ func GetAuthContext(c *gin.Context) *AuthContext {
 	}
 
 	authHeader := c.GetHeader(constants.AuthorizationHeader)
-	ctx.APIKeyProvided = authHeader != ""
+	ctx.APIKeyProvided = authHeader != "" || alternateAPIKey(c) != ""
 
 	if userID, exists := c.Get("user_id"); exists {
 		ctx.UserID = userID.(string)
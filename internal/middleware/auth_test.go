@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const testAPIKey = "sk-test-key"
+
+// newAuthTestEngine serves /v1/ping with the auth middleware and /v1beta/ping with the query key allowed
+func newAuthTestEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	v1 := engine.Group("/v1")
+	v1.Use(AuthMiddleware(testAPIKey))
+	v1.GET("/ping", ok)
+
+	v1beta := engine.Group("/v1beta")
+	v1beta.Use(QueryAPIKeyMiddleware())
+	v1beta.Use(AuthMiddleware(testAPIKey))
+	v1beta.GET("/ping", ok)
+	return engine
+}
+
+func TestAuthMiddlewareAPIKeySources(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		value  string
+		status int
+	}{
+		{name: "bearer token", path: "/v1/ping", header: "Authorization", value: "Bearer " + testAPIKey, status: http.StatusOK},
+		{name: "x-api-key header", path: "/v1/ping", header: "x-api-key", value: testAPIKey, status: http.StatusOK},
+		{name: "x-goog-api-key header", path: "/v1/ping", header: "x-goog-api-key", value: testAPIKey, status: http.StatusOK},
+		{name: "WebSocket subprotocol", path: "/v1/ping", header: "Sec-WebSocket-Protocol", value: "chat, api-key." + testAPIKey, status: http.StatusOK},
+		{name: "wrong key", path: "/v1/ping", header: "x-api-key", value: "sk-wrong", status: http.StatusUnauthorized},
+		{name: "query key on v1beta", path: "/v1beta/ping?key=" + testAPIKey, status: http.StatusOK},
+		{name: "query key on v1", path: "/v1/ping?key=" + testAPIKey, status: http.StatusUnauthorized},
+		{name: "wrong query key on v1beta", path: "/v1beta/ping?key=sk-wrong", status: http.StatusUnauthorized},
+	}
+
+	engine := newAuthTestEngine()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestRedactAPIKey(t *testing.T) {
+	assert.Equal(t, "/v1beta/models/gemini-2.5-flash:generateContent?key=REDACTED",
+		redactAPIKey("/v1beta/models/gemini-2.5-flash:generateContent?key="+testAPIKey))
+	assert.Equal(t, "/v1beta/ping?alt=sse&key=REDACTED&foo=bar", redactAPIKey("/v1beta/ping?alt=sse&key="+testAPIKey+"&foo=bar"))
+	assert.Equal(t, "/v1/ping?monkey=1", redactAPIKey("/v1/ping?monkey=1"))
+	assert.Equal(t, "/v1/ping", redactAPIKey("/v1/ping"))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -21,7 +22,7 @@ func LoggingMiddleware() gin.HandlerFunc {
 			param.ClientIP,
 			param.TimeStamp.Format(time.RFC3339),
 			param.Method,
-			param.Path,
+			redactAPIKey(param.Path),
 			param.Request.Proto,
 			param.StatusCode,
 			param.Latency,
@@ -31,6 +32,14 @@ func LoggingMiddleware() gin.HandlerFunc {
 	})
 }
 
+// apiKeyQueryPattern matches the value of the key query parameter
+var apiKeyQueryPattern = regexp.MustCompile(`([?&]` + constants.APIKeyQueryParam + `=)[^&]*`)
+
+// redactAPIKey hides the API key sent as a query parameter so it never reaches the logs
+func redactAPIKey(path string) string {
+	return apiKeyQueryPattern.ReplaceAllString(path, "${1}REDACTED")
+}
+
 // RequestLoggingMiddleware logs detailed request information
 func RequestLoggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -64,7 +73,7 @@ func logRequest(c *gin.Context, path, raw string, bodyBytes []byte, latency time
 	userAgent := c.Request.UserAgent()
 	
 	if raw != "" {
-		path = path + "?" + raw
+		path = redactAPIKey(path + "?" + raw)
 	}
 
 	// Basic request info
@@ -4,29 +4,42 @@ import (
 	"fmt"
 	"gemini-cli-go/internal/constants"
 	"gemini-cli-go/internal/types"
+	"sort"
+	"time"
 )
 
 // GeminiCliModels contains configuration for all supported Gemini models
 var GeminiCliModels = map[string]types.ModelInfo{
 	"gemini-2.5-pro": {
-		MaxTokens:           65536,
-		ContextWindow:       1048576,
-		SupportsImages:      true,
-		SupportsPromptCache: false,
-		InputPrice:          0,
-		OutputPrice:         0,
-		Description:         "Google's Gemini 2.5 Pro model via OAuth (free tier)",
-		Thinking:            true,
+		MaxTokens:            65536,
+		ContextWindow:        1048576,
+		SupportsImages:       true,
+		SupportsPromptCache:  false,
+		InputPrice:           0,
+		OutputPrice:          0,
+		Description:          "Google's Gemini 2.5 Pro model via OAuth (free tier)",
+		Thinking:             true,
+		ThinkingBudgetMin:    128,
+		ThinkingBudgetMax:    32768,
+		ThinkingCanDisable:   false,
+		ThinkingBudgetLow:    2048,
+		ThinkingBudgetMedium: 16384,
+		Fallbacks:            []string{"gemini-2.5-flash"},
 	},
 	"gemini-2.5-flash": {
-		MaxTokens:           65536,
-		ContextWindow:       1048576,
-		SupportsImages:      true,
-		SupportsPromptCache: false,
-		InputPrice:          0,
-		OutputPrice:         0,
-		Description:         "Google's Gemini 2.5 Flash model via OAuth (free tier)",
-		Thinking:            true,
+		MaxTokens:            65536,
+		ContextWindow:        1048576,
+		SupportsImages:       true,
+		SupportsPromptCache:  false,
+		InputPrice:           0,
+		OutputPrice:          0,
+		Description:          "Google's Gemini 2.5 Flash model via OAuth (free tier)",
+		Thinking:             true,
+		ThinkingBudgetMin:    1,
+		ThinkingBudgetMax:    24576,
+		ThinkingCanDisable:   true,
+		ThinkingBudgetLow:    1024,
+		ThinkingBudgetMedium: 8192,
 	},
 	"gemini-2.0-flash-001": {
 		MaxTokens:           65536,
@@ -98,6 +111,44 @@ func GetAllModelIDs() []string {
 	return modelIDs
 }
 
+// GetModelList returns all available models in OpenAI list format
+func GetModelList() types.ModelListResponse {
+	modelIDs := GetAllModelIDs()
+	sort.Strings(modelIDs)
+
+	created := time.Now().Unix()
+	data := make([]types.ModelItem, 0, len(modelIDs))
+	for _, modelID := range modelIDs {
+		data = append(data, types.ModelItem{
+			ID:      modelID,
+			Object:  constants.OpenAIModelObject,
+			Created: created,
+			OwnedBy: constants.OpenAIModelOwner,
+		})
+	}
+
+	return types.ModelListResponse{
+		Object: constants.OpenAIModelListObject,
+		Data:   data,
+	}
+}
+
+// GetFallbackChain returns the model followed by the models to fall back to, in order
+func GetFallbackChain(modelID string) []string {
+	chain := []string{modelID}
+	info, exists := GeminiCliModels[modelID]
+	if !exists {
+		return chain
+	}
+
+	for _, fallback := range info.Fallbacks {
+		if fallback != modelID && IsValidModel(fallback) {
+			chain = append(chain, fallback)
+		}
+	}
+	return chain
+}
+
 // IsValidModel checks if the given model ID is valid
 func IsValidModel(modelID string) bool {
 	_, exists := GeminiCliModels[modelID]
@@ -127,6 +178,47 @@ func SupportsThinking(modelID string) bool {
 	return info.Thinking
 }
 
+// ClampThinkingBudget returns the budget the model accepts for the requested thinking budget.
+// -1 keeps the dynamic budget, 0 disables thinking on models that allow it and is raised to
+// the minimum otherwise.
+func ClampThinkingBudget(modelID string, budget int) int {
+	info, exists := GeminiCliModels[modelID]
+	if !exists || budget < 0 {
+		return constants.DefaultThinkingBudget
+	}
+
+	if budget == constants.DisabledThinkingBudget && info.ThinkingCanDisable {
+		return budget
+	}
+	if budget < info.ThinkingBudgetMin {
+		return info.ThinkingBudgetMin
+	}
+	if info.ThinkingBudgetMax > 0 && budget > info.ThinkingBudgetMax {
+		return info.ThinkingBudgetMax
+	}
+	return budget
+}
+
+// ReasoningEffortBudget returns the thinking budget of the model for an OpenAI reasoning effort
+func ReasoningEffortBudget(modelID string, effort string) (int, error) {
+	info := GeminiCliModels[modelID]
+
+	switch effort {
+	case constants.ReasoningEffortNone:
+		return constants.DisabledThinkingBudget, nil
+	case constants.ReasoningEffortMinimal:
+		return info.ThinkingBudgetMin, nil
+	case constants.ReasoningEffortLow:
+		return info.ThinkingBudgetLow, nil
+	case constants.ReasoningEffortMedium:
+		return info.ThinkingBudgetMedium, nil
+	case constants.ReasoningEffortHigh:
+		return info.ThinkingBudgetMax, nil
+	default:
+		return 0, fmt.Errorf("unsupported reasoning_effort: %s", effort)
+	}
+}
+
 // GetMaxTokens returns the maximum number of tokens for the model
 func GetMaxTokens(modelID string) int {
 	info, exists := GeminiCliModels[modelID]
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+
+	"gemini-cli-go/internal/constants"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReasoningEffortBudget(t *testing.T) {
+	tests := []struct {
+		model   string
+		budgets map[string]int
+	}{
+		{
+			model: "gemini-2.5-pro",
+			budgets: map[string]int{
+				constants.ReasoningEffortMinimal: 128,
+				constants.ReasoningEffortLow:     2048,
+				constants.ReasoningEffortMedium:  16384,
+				constants.ReasoningEffortHigh:    32768,
+			},
+		},
+		{
+			model: "gemini-2.5-flash",
+			budgets: map[string]int{
+				constants.ReasoningEffortNone:    0,
+				constants.ReasoningEffortMinimal: 1,
+				constants.ReasoningEffortLow:     1024,
+				constants.ReasoningEffortMedium:  8192,
+				constants.ReasoningEffortHigh:    24576,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			info, ok := GetModelInfo(tt.model)
+			require.True(t, ok)
+			for effort, expected := range tt.budgets {
+				budget, err := ReasoningEffortBudget(tt.model, effort)
+				require.NoError(t, err)
+				assert.Equal(t, expected, budget, effort)
+				assert.Equal(t, budget, ClampThinkingBudget(tt.model, budget), "%s budget is outside the model's range", effort)
+				if effort != constants.ReasoningEffortNone {
+					assert.GreaterOrEqual(t, budget, info.ThinkingBudgetMin)
+				}
+			}
+		})
+	}
+
+	_, err := ReasoningEffortBudget("gemini-2.5-flash", "extreme")
+	assert.Error(t, err)
+}
@@ -9,6 +9,7 @@ import (
 	"gemini-cli-go/internal/gemini"
 	"gemini-cli-go/internal/handlers"
 	"gemini-cli-go/internal/middleware"
+	"gemini-cli-go/internal/store"
 
 	"github.com/gin-gonic/gin"
 )
@@ -34,6 +35,9 @@ func SetupRoutes(cfg *config.Config) *gin.Engine {
 
 	// Create handlers
 	openaiHandler := handlers.NewOpenAIHandler(cfg, authManager, geminiClient)
+	anthropicHandler := handlers.NewAnthropicHandler(cfg, geminiClient)
+	geminiHandler := handlers.NewGeminiHandler(cfg, geminiClient)
+	responsesHandler := handlers.NewResponsesHandler(cfg, geminiClient, store.NewMemoryResponseStore(cfg.GetResponseStoreSize(), cfg.GetResponseStoreTTL()))
 	debugHandler := handlers.NewDebugHandler(cfg, authManager)
 
 	// Root endpoint
@@ -51,6 +55,13 @@ func SetupRoutes(cfg *config.Config) *gin.Engine {
 		// OpenAI-compatible endpoints
 		v1.GET(constants.PathModels, openaiHandler.ListModels)
 		v1.POST(constants.PathChatCompletions, openaiHandler.ChatCompletions)
+		v1.GET(constants.PathChatWebSocket, openaiHandler.ChatWebSocket)
+		v1.POST(constants.PathCompletions, openaiHandler.Completions)
+		v1.POST(constants.PathResponses, responsesHandler.CreateResponse)
+
+		// Anthropic-compatible endpoints
+		v1.POST(constants.PathMessages, anthropicHandler.Messages)
+		v1.POST(constants.PathMessagesCountTokens, anthropicHandler.CountTokens)
 
 		// Debug endpoints
 		debug := v1.Group(constants.PathDebug)
@@ -67,6 +78,14 @@ func SetupRoutes(cfg *config.Config) *gin.Engine {
 		v1.POST(constants.PathTest, debugHandler.FullTest)
 	}
 
+	// Native Gemini API routes
+	v1beta := engine.Group(constants.PathV1Beta)
+	{
+		v1beta.Use(middleware.QueryAPIKeyMiddleware())
+		v1beta.Use(middleware.AuthMiddleware(cfg.GetOpenAIAPIKey()))
+		v1beta.POST(constants.PathNativeModelAction, geminiHandler.ModelAction)
+	}
+
 	return engine
 }
 
@@ -88,6 +107,9 @@ func SetupTestRoutes(cfg *config.Config) *gin.Engine {
 
 	// Create handlers
 	openaiHandler := handlers.NewOpenAIHandler(cfg, authManager, geminiClient)
+	anthropicHandler := handlers.NewAnthropicHandler(cfg, geminiClient)
+	geminiHandler := handlers.NewGeminiHandler(cfg, geminiClient)
+	responsesHandler := handlers.NewResponsesHandler(cfg, geminiClient, store.NewMemoryResponseStore(cfg.GetResponseStoreSize(), cfg.GetResponseStoreTTL()))
 	debugHandler := handlers.NewDebugHandler(cfg, authManager)
 
 	// Root endpoint
@@ -105,6 +127,13 @@ func SetupTestRoutes(cfg *config.Config) *gin.Engine {
 		// OpenAI-compatible endpoints
 		v1.GET(constants.PathModels, openaiHandler.ListModels)
 		v1.POST(constants.PathChatCompletions, openaiHandler.ChatCompletions)
+		v1.GET(constants.PathChatWebSocket, openaiHandler.ChatWebSocket)
+		v1.POST(constants.PathCompletions, openaiHandler.Completions)
+		v1.POST(constants.PathResponses, responsesHandler.CreateResponse)
+
+		// Anthropic-compatible endpoints
+		v1.POST(constants.PathMessages, anthropicHandler.Messages)
+		v1.POST(constants.PathMessagesCountTokens, anthropicHandler.CountTokens)
 
 		// Debug endpoints
 		debug := v1.Group(constants.PathDebug)
@@ -121,6 +150,14 @@ func SetupTestRoutes(cfg *config.Config) *gin.Engine {
 		v1.POST(constants.PathTest, debugHandler.FullTest)
 	}
 
+	// Native Gemini API routes
+	v1beta := engine.Group(constants.PathV1Beta)
+	{
+		v1beta.Use(middleware.QueryAPIKeyMiddleware())
+		v1beta.Use(middleware.OptionalAuthMiddleware(cfg.GetOpenAIAPIKey()))
+		v1beta.POST(constants.PathNativeModelAction, geminiHandler.ModelAction)
+	}
+
 	return engine
 }
 
@@ -148,6 +185,9 @@ func SetupProductionRoutes(cfg *config.Config) *gin.Engine {
 
 	// Create handlers
 	openaiHandler := handlers.NewOpenAIHandler(cfg, authManager, geminiClient)
+	anthropicHandler := handlers.NewAnthropicHandler(cfg, geminiClient)
+	geminiHandler := handlers.NewGeminiHandler(cfg, geminiClient)
+	responsesHandler := handlers.NewResponsesHandler(cfg, geminiClient, store.NewMemoryResponseStore(cfg.GetResponseStoreSize(), cfg.GetResponseStoreTTL()))
 	debugHandler := handlers.NewDebugHandler(cfg, authManager)
 
 	// Root endpoint
@@ -165,6 +205,13 @@ func SetupProductionRoutes(cfg *config.Config) *gin.Engine {
 		// OpenAI-compatible endpoints
 		v1.GET(constants.PathModels, openaiHandler.ListModels)
 		v1.POST(constants.PathChatCompletions, openaiHandler.ChatCompletions)
+		v1.GET(constants.PathChatWebSocket, openaiHandler.ChatWebSocket)
+		v1.POST(constants.PathCompletions, openaiHandler.Completions)
+		v1.POST(constants.PathResponses, responsesHandler.CreateResponse)
+
+		// Anthropic-compatible endpoints
+		v1.POST(constants.PathMessages, anthropicHandler.Messages)
+		v1.POST(constants.PathMessagesCountTokens, anthropicHandler.CountTokens)
 
 		// Limited debug endpoints in production
 		debug := v1.Group(constants.PathDebug)
@@ -178,5 +225,13 @@ func SetupProductionRoutes(cfg *config.Config) *gin.Engine {
 		}
 	}
 
+	// Native Gemini API routes
+	v1beta := engine.Group(constants.PathV1Beta)
+	{
+		v1beta.Use(middleware.QueryAPIKeyMiddleware())
+		v1beta.Use(middleware.RequireAuthMiddleware(cfg.GetOpenAIAPIKey()))
+		v1beta.POST(constants.PathNativeModelAction, geminiHandler.ModelAction)
+	}
+
 	return engine
 }
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"gemini-cli-go/internal/types"
+)
+
+// StoredResponse is a response kept for previous_response_id chaining
+type StoredResponse struct {
+	Response  types.ResponsesResponse
+	Messages  []types.ChatMessage
+	CreatedAt time.Time
+}
+
+// ResponseStore stores responses so later requests can continue the conversation
+type ResponseStore interface {
+	Get(id string) (*StoredResponse, bool)
+	Put(id string, response *StoredResponse)
+	Delete(id string)
+}
+
+// MemoryResponseStore keeps the most recent responses in memory, evicting the oldest
+// once capacity is reached and entries older than ttl
+type MemoryResponseStore struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+// memoryEntry is an element of the eviction list
+type memoryEntry struct {
+	id       string
+	response *StoredResponse
+}
+
+// NewMemoryResponseStore creates an in-memory response store, a zero ttl keeps entries until evicted
+func NewMemoryResponseStore(capacity int, ttl time.Duration) *MemoryResponseStore {
+	return &MemoryResponseStore{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the stored response with the given ID
+func (s *MemoryResponseStore) Get(id string) (*StoredResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[id]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*memoryEntry)
+	if s.ttl > 0 && time.Since(entry.response.CreatedAt) > s.ttl {
+		s.remove(element)
+		return nil, false
+	}
+	return entry.response, true
+}
+
+// Put stores a response, evicting the oldest one when the store is full
+func (s *MemoryResponseStore) Put(id string, response *StoredResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[id]; ok {
+		s.remove(element)
+	}
+
+	s.entries[id] = s.order.PushBack(&memoryEntry{id: id, response: response})
+
+	for s.capacity > 0 && s.order.Len() > s.capacity {
+		s.remove(s.order.Front())
+	}
+}
+
+// Delete removes a stored response
+func (s *MemoryResponseStore) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[id]; ok {
+		s.remove(element)
+	}
+}
+
+// remove removes an element from the store, the caller must hold the lock
+func (s *MemoryResponseStore) remove(element *list.Element) {
+	entry := s.order.Remove(element).(*memoryEntry)
+	delete(s.entries, entry.id)
+}
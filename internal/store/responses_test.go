@@ -0,0 +1,45 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryResponseStoreEvictsOldest(t *testing.T) {
+	s := NewMemoryResponseStore(2, 0)
+	s.Put("a", &StoredResponse{CreatedAt: time.Now()})
+	s.Put("b", &StoredResponse{CreatedAt: time.Now()})
+	s.Put("c", &StoredResponse{CreatedAt: time.Now()})
+
+	if _, ok := s.Get("a"); ok {
+		t.Fatal("expected the oldest response to be evicted")
+	}
+	for _, id := range []string{"b", "c"} {
+		if _, ok := s.Get(id); !ok {
+			t.Fatalf("expected response %s to be stored", id)
+		}
+	}
+}
+
+func TestMemoryResponseStoreExpiresEntries(t *testing.T) {
+	s := NewMemoryResponseStore(10, time.Minute)
+	s.Put("old", &StoredResponse{CreatedAt: time.Now().Add(-2 * time.Minute)})
+	s.Put("new", &StoredResponse{CreatedAt: time.Now()})
+
+	if _, ok := s.Get("old"); ok {
+		t.Fatal("expected the expired response to be dropped")
+	}
+	if _, ok := s.Get("new"); !ok {
+		t.Fatal("expected the recent response to be stored")
+	}
+}
+
+func TestMemoryResponseStoreDelete(t *testing.T) {
+	s := NewMemoryResponseStore(10, 0)
+	s.Put("a", &StoredResponse{CreatedAt: time.Now()})
+	s.Delete("a")
+
+	if _, ok := s.Get("a"); ok {
+		t.Fatal("expected the deleted response to be gone")
+	}
+}
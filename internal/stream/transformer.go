@@ -1,6 +1,7 @@
 package stream
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -18,20 +19,82 @@ type Transformer struct {
 	completionID  string
 	chunkIndex    int
 	created       int64
+	toolCallIndex map[int]int
+
+	// started tracks the choices that have been opened with a role chunk
+	started map[int]bool
+
+	// reasoningFormat selects where reasoning is placed, thinkOpen tracks open <think> tags per choice
+	reasoningFormat string
+	thinkOpen       map[int]bool
+
+	// usage is the latest usage reported by Gemini, sent in a final chunk by UsageChunk
+	usage *types.UsageData
 }
 
 // NewTransformer creates a new stream transformer
 func NewTransformer(model string) *Transformer {
 	return &Transformer{
-		model:        model,
-		completionID: constants.ChatCompletionIDPrefix + strings.ReplaceAll(uuid.New().String(), "-", ""),
-		chunkIndex:   0,
-		created:      time.Now().Unix(),
+		model:         model,
+		completionID:  constants.ChatCompletionIDPrefix + strings.ReplaceAll(uuid.New().String(), "-", ""),
+		chunkIndex:    0,
+		created:       time.Now().Unix(),
+		toolCallIndex: make(map[int]int),
+		started:       make(map[int]bool),
+
+		reasoningFormat: constants.ReasoningFormatReasoning,
+		thinkOpen:       make(map[int]bool),
 	}
 }
 
-// Transform converts a Gemini stream chunk to OpenAI format
+// SetModel changes the model reported in subsequent chunks, e.g. after a fallback
+func (t *Transformer) SetModel(model string) {
+	t.model = model
+}
+
+// SetReasoningFormat selects how reasoning is streamed: in delta.reasoning, in
+// delta.reasoning_content or as content wrapped in <think> tags
+func (t *Transformer) SetReasoningFormat(format string) {
+	t.reasoningFormat = format
+}
+
+// Transform converts a Gemini stream chunk to an OpenAI SSE chunk, which is empty for usage chunks
 func (t *Transformer) Transform(chunk types.StreamChunk) (string, error) {
+	response, err := t.TransformChunk(chunk)
+	if err != nil || response == nil {
+		return "", err
+	}
+
+	return t.formatSSEChunk(response)
+}
+
+// TransformChunks converts a Gemini stream chunk to the OpenAI chunk objects to send, preceded by
+// a role chunk when it is the first chunk of its choice. Usage chunks are only recorded.
+func (t *Transformer) TransformChunks(chunk types.StreamChunk) ([]*types.ChatCompletionResponse, error) {
+	if chunk.Type == types.StreamChunkTypeUsage {
+		_, err := t.TransformChunk(chunk)
+		return nil, err
+	}
+
+	var responses []*types.ChatCompletionResponse
+	if !t.started[chunk.Index] {
+		responses = append(responses, t.RoleChunk(chunk.Index))
+		t.started[chunk.Index] = true
+	}
+
+	response, err := t.TransformChunk(chunk)
+	if err != nil {
+		return nil, err
+	}
+	if response != nil {
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// TransformChunk converts a Gemini stream chunk to an OpenAI chunk object. Usage chunks are
+// recorded for UsageChunk and produce no chunk object.
+func (t *Transformer) TransformChunk(chunk types.StreamChunk) (*types.ChatCompletionResponse, error) {
 	t.chunkIndex++
 
 	switch chunk.Type {
@@ -43,19 +106,28 @@ func (t *Transformer) Transform(chunk types.StreamChunk) (string, error) {
 		return t.transformThinkingContentChunk(chunk)
 	case types.StreamChunkTypeRealThinking:
 		return t.transformRealThinkingChunk(chunk)
+	case types.StreamChunkTypeToolCall:
+		return t.transformToolCallChunk(chunk)
+	case types.StreamChunkTypeAnnotations:
+		return t.transformAnnotationsChunk(chunk)
+	case types.StreamChunkTypeCodeExecution:
+		return t.transformCodeExecutionChunk(chunk)
+	case types.StreamChunkTypeFinishReason:
+		return t.transformFinishReasonChunk(chunk)
 	case types.StreamChunkTypeUsage:
 		return t.transformUsageChunk(chunk)
 	default:
-		return "", fmt.Errorf("unknown chunk type: %s", chunk.Type)
+		return nil, fmt.Errorf("unknown chunk type: %s", chunk.Type)
 	}
 }
 
 // transformTextChunk transforms a text chunk to OpenAI format
-func (t *Transformer) transformTextChunk(chunk types.StreamChunk) (string, error) {
+func (t *Transformer) transformTextChunk(chunk types.StreamChunk) (*types.ChatCompletionResponse, error) {
 	text, ok := chunk.Data.(string)
 	if !ok {
-		return "", fmt.Errorf("invalid text chunk data type")
+		return nil, fmt.Errorf("invalid text chunk data type")
 	}
+	text = t.closeThink(chunk.Index) + text
 
 	response := types.ChatCompletionResponse{
 		ID:      t.completionID,
@@ -64,7 +136,7 @@ func (t *Transformer) transformTextChunk(chunk types.StreamChunk) (string, error
 		Model:   t.model,
 		Choices: []types.ChatCompletionChoice{
 			{
-				Index: 0,
+				Index: chunk.Index,
 				Delta: &types.ChatCompletionDelta{
 					Content: text,
 				},
@@ -73,14 +145,14 @@ func (t *Transformer) transformTextChunk(chunk types.StreamChunk) (string, error
 		},
 	}
 
-	return t.formatSSEChunk(response)
+	return &response, nil
 }
 
 // transformReasoningChunk transforms a reasoning chunk to OpenAI format
-func (t *Transformer) transformReasoningChunk(chunk types.StreamChunk) (string, error) {
+func (t *Transformer) transformReasoningChunk(chunk types.StreamChunk) (*types.ChatCompletionResponse, error) {
 	reasoningData, ok := chunk.Data.(types.ReasoningData)
 	if !ok {
-		return "", fmt.Errorf("invalid reasoning chunk data type")
+		return nil, fmt.Errorf("invalid reasoning chunk data type")
 	}
 
 	response := types.ChatCompletionResponse{
@@ -90,23 +162,21 @@ func (t *Transformer) transformReasoningChunk(chunk types.StreamChunk) (string,
 		Model:   t.model,
 		Choices: []types.ChatCompletionChoice{
 			{
-				Index: 0,
-				Delta: &types.ChatCompletionDelta{
-					Reasoning: reasoningData.Reasoning,
-				},
+				Index:        chunk.Index,
+				Delta:        t.reasoningDelta(chunk.Index, reasoningData.Reasoning),
 				FinishReason: nil,
 			},
 		},
 	}
 
-	return t.formatSSEChunk(response)
+	return &response, nil
 }
 
 // transformThinkingContentChunk transforms thinking content to OpenAI format
-func (t *Transformer) transformThinkingContentChunk(chunk types.StreamChunk) (string, error) {
+func (t *Transformer) transformThinkingContentChunk(chunk types.StreamChunk) (*types.ChatCompletionResponse, error) {
 	text, ok := chunk.Data.(string)
 	if !ok {
-		return "", fmt.Errorf("invalid thinking content chunk data type")
+		return nil, fmt.Errorf("invalid thinking content chunk data type")
 	}
 
 	response := types.ChatCompletionResponse{
@@ -116,7 +186,7 @@ func (t *Transformer) transformThinkingContentChunk(chunk types.StreamChunk) (st
 		Model:   t.model,
 		Choices: []types.ChatCompletionChoice{
 			{
-				Index: 0,
+				Index: chunk.Index,
 				Delta: &types.ChatCompletionDelta{
 					Content: text,
 				},
@@ -125,16 +195,71 @@ func (t *Transformer) transformThinkingContentChunk(chunk types.StreamChunk) (st
 		},
 	}
 
-	return t.formatSSEChunk(response)
+	return &response, nil
 }
 
 // transformRealThinkingChunk transforms real thinking to OpenAI format
-func (t *Transformer) transformRealThinkingChunk(chunk types.StreamChunk) (string, error) {
+func (t *Transformer) transformRealThinkingChunk(chunk types.StreamChunk) (*types.ChatCompletionResponse, error) {
 	text, ok := chunk.Data.(string)
 	if !ok {
-		return "", fmt.Errorf("invalid real thinking chunk data type")
+		return nil, fmt.Errorf("invalid real thinking chunk data type")
+	}
+
+	response := types.ChatCompletionResponse{
+		ID:      t.completionID,
+		Object:  constants.OpenAIChatCompletionObject,
+		Created: t.created,
+		Model:   t.model,
+		Choices: []types.ChatCompletionChoice{
+			{
+				Index:        chunk.Index,
+				Delta:        t.reasoningDelta(chunk.Index, text),
+				FinishReason: nil,
+			},
+		},
+	}
+
+	return &response, nil
+}
+
+// reasoningDelta places reasoning text according to the reasoning format
+func (t *Transformer) reasoningDelta(index int, text string) *types.ChatCompletionDelta {
+	switch t.reasoningFormat {
+	case constants.ReasoningFormatReasoningContent:
+		return &types.ChatCompletionDelta{ReasoningContent: text}
+	case constants.ReasoningFormatThinkTags:
+		if !t.thinkOpen[index] {
+			t.thinkOpen[index] = true
+			text = constants.ThinkOpenTag + text
+		}
+		return &types.ChatCompletionDelta{Content: text}
+	default:
+		return &types.ChatCompletionDelta{Reasoning: text}
+	}
+}
+
+// closeThink returns the closing </think> tag if the choice's reasoning is still open
+func (t *Transformer) closeThink(index int) string {
+	if !t.thinkOpen[index] {
+		return ""
+	}
+	delete(t.thinkOpen, index)
+	return constants.ThinkCloseTag
+}
+
+// transformToolCallChunk transforms a tool call to OpenAI format
+func (t *Transformer) transformToolCallChunk(chunk types.StreamChunk) (*types.ChatCompletionResponse, error) {
+	toolCall, ok := chunk.Data.(types.ToolCall)
+	if !ok {
+		return nil, fmt.Errorf("invalid tool call chunk data type")
 	}
 
+	// Gemini sends complete function calls, so each one is streamed as a single delta,
+	// tool calls are numbered per choice
+	index := t.toolCallIndex[chunk.Index]
+	t.toolCallIndex[chunk.Index]++
+	toolCall.Index = &index
+
 	response := types.ChatCompletionResponse{
 		ID:      t.completionID,
 		Object:  constants.OpenAIChatCompletionObject,
@@ -142,23 +267,24 @@ func (t *Transformer) transformRealThinkingChunk(chunk types.StreamChunk) (strin
 		Model:   t.model,
 		Choices: []types.ChatCompletionChoice{
 			{
-				Index: 0,
+				Index: chunk.Index,
 				Delta: &types.ChatCompletionDelta{
-					Reasoning: text,
+					Content:   t.closeThink(chunk.Index),
+					ToolCalls: []types.ToolCall{toolCall},
 				},
 				FinishReason: nil,
 			},
 		},
 	}
 
-	return t.formatSSEChunk(response)
+	return &response, nil
 }
 
-// transformUsageChunk transforms usage data to OpenAI format
-func (t *Transformer) transformUsageChunk(chunk types.StreamChunk) (string, error) {
-	usageData, ok := chunk.Data.(types.UsageData)
+// transformAnnotationsChunk transforms URL citations to OpenAI format
+func (t *Transformer) transformAnnotationsChunk(chunk types.StreamChunk) (*types.ChatCompletionResponse, error) {
+	annotations, ok := chunk.Data.([]types.Annotation)
 	if !ok {
-		return "", fmt.Errorf("invalid usage chunk data type")
+		return nil, fmt.Errorf("invalid annotations chunk data type")
 	}
 
 	response := types.ChatCompletionResponse{
@@ -168,19 +294,95 @@ func (t *Transformer) transformUsageChunk(chunk types.StreamChunk) (string, erro
 		Model:   t.model,
 		Choices: []types.ChatCompletionChoice{
 			{
-				Index:        0,
-				Delta:        &types.ChatCompletionDelta{},
-				FinishReason: stringPtr("stop"),
+				Index: chunk.Index,
+				Delta: &types.ChatCompletionDelta{
+					Annotations: annotations,
+				},
+				FinishReason: nil,
 			},
 		},
-		Usage: &types.ChatCompletionUsage{
-			PromptTokens:     usageData.InputTokens,
-			CompletionTokens: usageData.OutputTokens,
-			TotalTokens:      usageData.InputTokens + usageData.OutputTokens,
+	}
+
+	return &response, nil
+}
+
+// transformCodeExecutionChunk transforms code run by Gemini, or its result, to a code_execution delta
+func (t *Transformer) transformCodeExecutionChunk(chunk types.StreamChunk) (*types.ChatCompletionResponse, error) {
+	execution, ok := chunk.Data.(types.CodeExecution)
+	if !ok {
+		return nil, fmt.Errorf("invalid code execution chunk data type")
+	}
+
+	response := types.ChatCompletionResponse{
+		ID:      t.completionID,
+		Object:  constants.OpenAIChatCompletionObject,
+		Created: t.created,
+		Model:   t.model,
+		Choices: []types.ChatCompletionChoice{
+			{
+				Index: chunk.Index,
+				Delta: &types.ChatCompletionDelta{
+					CodeExecution: []types.CodeExecution{execution},
+				},
+				FinishReason: nil,
+			},
 		},
 	}
 
-	return t.formatSSEChunk(response)
+	return &response, nil
+}
+
+// transformFinishReasonChunk transforms a finish reason to OpenAI format
+func (t *Transformer) transformFinishReasonChunk(chunk types.StreamChunk) (*types.ChatCompletionResponse, error) {
+	finish, ok := chunk.Data.(types.FinishData)
+	if !ok {
+		return nil, fmt.Errorf("invalid finish reason chunk data type")
+	}
+
+	response := types.ChatCompletionResponse{
+		ID:      t.completionID,
+		Object:  constants.OpenAIChatCompletionObject,
+		Created: t.created,
+		Model:   t.model,
+		Choices: []types.ChatCompletionChoice{
+			{
+				Index:         chunk.Index,
+				Delta:         &types.ChatCompletionDelta{Content: t.closeThink(chunk.Index)},
+				FinishReason:  stringPtr(finish.FinishReason),
+				SafetyRatings: finish.SafetyRatings,
+			},
+		},
+	}
+
+	return &response, nil
+}
+
+// transformUsageChunk records usage data, Gemini reports the running totals on every response
+func (t *Transformer) transformUsageChunk(chunk types.StreamChunk) (*types.ChatCompletionResponse, error) {
+	usageData, ok := chunk.Data.(types.UsageData)
+	if !ok {
+		return nil, fmt.Errorf("invalid usage chunk data type")
+	}
+
+	t.usage = &usageData
+	return nil, nil
+}
+
+// UsageChunk creates the final usage-only chunk sent for stream_options.include_usage,
+// or nil if no usage was reported
+func (t *Transformer) UsageChunk() *types.ChatCompletionResponse {
+	if t.usage == nil {
+		return nil
+	}
+
+	return &types.ChatCompletionResponse{
+		ID:      t.completionID,
+		Object:  constants.OpenAIChatCompletionObject,
+		Created: t.created,
+		Model:   t.model,
+		Choices: []types.ChatCompletionChoice{},
+		Usage:   t.usage.ChatCompletionUsage(),
+	}
 }
 
 // CreateFinalChunk creates the final [DONE] chunk
@@ -188,16 +390,21 @@ func (t *Transformer) CreateFinalChunk() string {
 	return constants.SSEDoneMessage + constants.SSENewLine + constants.SSENewLine
 }
 
-// CreateRoleChunk creates a role chunk (first chunk with role)
-func (t *Transformer) CreateRoleChunk() (string, error) {
-	response := types.ChatCompletionResponse{
+// CreateRoleChunk creates the SSE role chunk that opens the choice with the given index
+func (t *Transformer) CreateRoleChunk(index int) (string, error) {
+	return t.formatSSEChunk(t.RoleChunk(index))
+}
+
+// RoleChunk creates the role chunk object that opens the choice with the given index
+func (t *Transformer) RoleChunk(index int) *types.ChatCompletionResponse {
+	return &types.ChatCompletionResponse{
 		ID:      t.completionID,
 		Object:  constants.OpenAIChatCompletionObject,
 		Created: t.created,
 		Model:   t.model,
 		Choices: []types.ChatCompletionChoice{
 			{
-				Index: 0,
+				Index: index,
 				Delta: &types.ChatCompletionDelta{
 					Role: "assistant",
 				},
@@ -205,25 +412,26 @@ func (t *Transformer) CreateRoleChunk() (string, error) {
 			},
 		},
 	}
-
-	return t.formatSSEChunk(response)
 }
 
 // formatSSEChunk formats a response as SSE chunk
-func (t *Transformer) formatSSEChunk(response types.ChatCompletionResponse) (string, error) {
-	jsonData, err := json.Marshal(response)
-	if err != nil {
+func (t *Transformer) formatSSEChunk(response interface{}) (string, error) {
+	// OpenAI does not escape HTML characters, which matters for <think> tags
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(response); err != nil {
 		return "", fmt.Errorf("failed to marshal response: %w", err)
 	}
 
-	return constants.SSEDataPrefix + string(jsonData) + constants.SSENewLine + constants.SSENewLine, nil
+	return constants.SSEDataPrefix + strings.TrimSuffix(buffer.String(), "\n") + constants.SSENewLine + constants.SSENewLine, nil
 }
 
 // StreamWriter handles writing SSE data to a response writer
 type StreamWriter struct {
-	writer    ResponseWriter
+	writer      ResponseWriter
 	transformer *Transformer
-	hasStarted bool
+	hasStarted  bool
 }
 
 // ResponseWriter interface for writing streaming responses
@@ -244,6 +452,16 @@ func NewStreamWriter(writer ResponseWriter, model string) *StreamWriter {
 	}
 }
 
+// Transformer returns the transformer of the stream, e.g. to change the model or reasoning format
+func (sw *StreamWriter) Transformer() *Transformer {
+	return sw.transformer
+}
+
+// HasStarted returns true once the headers have been written
+func (sw *StreamWriter) HasStarted() bool {
+	return sw.hasStarted
+}
+
 // WriteHeaders sets the appropriate headers for SSE
 func (sw *StreamWriter) WriteHeaders() {
 	sw.writer.SetHeader("Content-Type", constants.ContentTypeSSE)
@@ -253,41 +471,59 @@ func (sw *StreamWriter) WriteHeaders() {
 	sw.writer.SetHeader("Access-Control-Allow-Methods", constants.CORSAllowMethods)
 	sw.writer.SetHeader("Access-Control-Allow-Headers", constants.CORSAllowHeaders)
 	sw.writer.WriteHeader(constants.StatusOK)
+	sw.hasStarted = true
 }
 
-// WriteChunk writes a chunk to the stream
+// WriteChunk writes a chunk to the stream, opening its choice with a role chunk first.
+// Usage is only recorded, it is sent by WriteUsageChunk.
 func (sw *StreamWriter) WriteChunk(chunk types.StreamChunk) error {
-	// Write role chunk first if this is the start
-	if !sw.hasStarted && chunk.Type == types.StreamChunkTypeText {
-		roleChunk, err := sw.transformer.CreateRoleChunk()
-		if err != nil {
-			return err
-		}
-		if _, err := sw.writer.Write([]byte(roleChunk)); err != nil {
+	responses, err := sw.transformer.TransformChunks(chunk)
+	if err != nil {
+		return err
+	}
+
+	for _, response := range responses {
+		if err := sw.WriteData(response); err != nil {
 			return err
 		}
-		sw.writer.Flush()
-		sw.hasStarted = true
 	}
+	return nil
+}
 
-	// Transform and write the chunk
-	sseChunk, err := sw.transformer.Transform(chunk)
-	if err != nil {
-		return err
+// WriteUsageChunk writes the final usage-only chunk, if any usage was reported
+func (sw *StreamWriter) WriteUsageChunk() error {
+	usage := sw.transformer.UsageChunk()
+	if usage == nil {
+		return nil
 	}
+	return sw.WriteData(usage)
+}
 
-	if _, err := sw.writer.Write([]byte(sseChunk)); err != nil {
+// WriteData writes any value as an SSE data event, e.g. an in-band error
+func (sw *StreamWriter) WriteData(data interface{}) error {
+	sseChunk, err := sw.transformer.formatSSEChunk(data)
+	if err != nil {
 		return err
 	}
-	sw.writer.Flush()
+	return sw.write(sseChunk)
+}
 
-	return nil
+// WriteHeartbeat writes an SSE comment, which clients ignore, to keep an idle connection open
+func (sw *StreamWriter) WriteHeartbeat() error {
+	return sw.write(constants.SSEHeartbeatMessage + "\n\n")
 }
 
 // WriteFinalChunk writes the final [DONE] chunk
 func (sw *StreamWriter) WriteFinalChunk() error {
-	finalChunk := sw.transformer.CreateFinalChunk()
-	if _, err := sw.writer.Write([]byte(finalChunk)); err != nil {
+	return sw.write(sw.transformer.CreateFinalChunk())
+}
+
+// write writes and flushes SSE data, writing the headers first if needed
+func (sw *StreamWriter) write(data string) error {
+	if !sw.hasStarted {
+		sw.WriteHeaders()
+	}
+	if _, err := sw.writer.Write([]byte(data)); err != nil {
 		return err
 	}
 	sw.writer.Flush()
@@ -400,6 +636,36 @@ func ValidateChunk(chunk types.StreamChunk) error {
 		if _, ok := chunk.Data.(string); !ok {
 			return fmt.Errorf("real thinking chunk must contain string data")
 		}
+	case types.StreamChunkTypeToolCall:
+		if _, ok := chunk.Data.(types.ToolCall); !ok {
+			return fmt.Errorf("tool call chunk must contain ToolCall")
+		}
+	case types.StreamChunkTypeFinishReason:
+		if _, ok := chunk.Data.(types.FinishData); !ok {
+			return fmt.Errorf("finish reason chunk must contain FinishData")
+		}
+	case types.StreamChunkTypeError:
+		if _, ok := chunk.Data.(error); !ok {
+			return fmt.Errorf("error chunk must contain an error")
+		}
+	case types.StreamChunkTypeModel:
+		if _, ok := chunk.Data.(string); !ok {
+			return fmt.Errorf("model chunk must contain string data")
+		}
+	case types.StreamChunkTypeAnnotations:
+		if _, ok := chunk.Data.([]types.Annotation); !ok {
+			return fmt.Errorf("annotations chunk must contain []Annotation")
+		}
+	case types.StreamChunkTypeCodeExecution:
+		if _, ok := chunk.Data.(types.CodeExecution); !ok {
+			return fmt.Errorf("code execution chunk must contain CodeExecution")
+		}
+	case types.StreamChunkTypeRaw:
+		if _, ok := chunk.Data.(json.RawMessage); !ok {
+			return fmt.Errorf("raw chunk must contain json.RawMessage")
+		}
+	case types.StreamChunkTypeHeartbeat:
+		// Heartbeats carry no data
 	default:
 		return fmt.Errorf("unknown chunk type: %s", chunk.Type)
 	}
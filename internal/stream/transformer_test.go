@@ -0,0 +1,94 @@
+package stream
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gemini-cli-go/internal/types"
+)
+
+// recorder is an in-memory ResponseWriter
+type recorder struct {
+	body    strings.Builder
+	headers map[string]string
+	status  int
+}
+
+func (r *recorder) Write(data []byte) (int, error) { return r.body.Write(data) }
+func (r *recorder) Flush()                         {}
+func (r *recorder) Header() map[string]string      { return r.headers }
+func (r *recorder) SetHeader(key, value string)    { r.headers[key] = value }
+func (r *recorder) WriteHeader(statusCode int)     { r.status = statusCode }
+
+func TestStreamWriterWritesOpenAIChunkSequence(t *testing.T) {
+	w := &recorder{headers: make(map[string]string)}
+	sw := NewStreamWriter(w, "gemini-2.5-flash")
+	id := sw.Transformer().completionID
+
+	chunks := []types.StreamChunk{
+		{Type: types.StreamChunkTypeText, Data: "a<b"},
+		{Type: types.StreamChunkTypeUsage, Data: types.UsageData{InputTokens: 3, OutputTokens: 2}},
+		{Type: types.StreamChunkTypeFinishReason, Data: types.FinishData{FinishReason: "stop"}},
+	}
+	for _, chunk := range chunks {
+		if err := sw.WriteChunk(chunk); err != nil {
+			t.Fatalf("WriteChunk failed: %v", err)
+		}
+	}
+	if err := sw.WriteUsageChunk(); err != nil {
+		t.Fatalf("WriteUsageChunk failed: %v", err)
+	}
+	if err := sw.WriteFinalChunk(); err != nil {
+		t.Fatalf("WriteFinalChunk failed: %v", err)
+	}
+
+	if w.status != 200 || w.headers["Content-Type"] != "text/event-stream" {
+		t.Fatalf("unexpected status %d and headers %v", w.status, w.headers)
+	}
+
+	prefix := `data: {"id":"` + id + `","object":"chat.completion.chunk","created":`
+	events := strings.Split(strings.TrimSuffix(w.body.String(), "\n\n"), "\n\n")
+	want := []string{
+		`"choices":[{"index":0,"delta":{"role":"assistant"},"finish_reason":null}]}`,
+		`"choices":[{"index":0,"delta":{"content":"a<b"},"finish_reason":null}]}`,
+		`"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+		`"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5,`,
+	}
+	if len(events) != len(want)+1 || events[len(events)-1] != "data: [DONE]" {
+		t.Fatalf("unexpected events:\n%s", w.body.String())
+	}
+	for i, fragment := range want {
+		if !strings.HasPrefix(events[i], prefix) || !strings.Contains(events[i], fragment) {
+			t.Errorf("event %d = %s, want %s", i, events[i], fragment)
+		}
+	}
+}
+
+func TestValidateChunk(t *testing.T) {
+	valid := []types.StreamChunk{
+		{Type: types.StreamChunkTypeText, Data: "a"},
+		{Type: types.StreamChunkTypeToolCall, Data: types.ToolCall{ID: "call_1"}},
+		{Type: types.StreamChunkTypeAnnotations, Data: []types.Annotation{{Type: "url_citation"}}},
+		{Type: types.StreamChunkTypeCodeExecution, Data: types.CodeExecution{Type: "executable_code"}},
+		{Type: types.StreamChunkTypeRaw, Data: json.RawMessage(`{}`)},
+		{Type: types.StreamChunkTypeHeartbeat},
+	}
+	for _, chunk := range valid {
+		if err := ValidateChunk(chunk); err != nil {
+			t.Errorf("ValidateChunk(%s) = %v, want nil", chunk.Type, err)
+		}
+	}
+
+	invalid := []types.StreamChunk{
+		{Type: types.StreamChunkTypeAnnotations, Data: "a"},
+		{Type: types.StreamChunkTypeCodeExecution, Data: "a"},
+		{Type: types.StreamChunkTypeRaw, Data: "a"},
+		{Type: "unknown"},
+	}
+	for _, chunk := range invalid {
+		if err := ValidateChunk(chunk); err == nil {
+			t.Errorf("ValidateChunk(%s) = nil, want an error", chunk.Type)
+		}
+	}
+}
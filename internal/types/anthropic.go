@@ -0,0 +1,140 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AnthropicMessagesRequest represents an Anthropic Messages API request
+type AnthropicMessagesRequest struct {
+	Model         string                 `json:"model" binding:"required"`
+	Messages      []AnthropicMessage     `json:"messages" binding:"required"`
+	System        AnthropicContent       `json:"system,omitempty"`
+	MaxTokens     int                    `json:"max_tokens,omitempty"`
+	Temperature   *float64               `json:"temperature,omitempty"`
+	TopP          *float64               `json:"top_p,omitempty"`
+	StopSequences []string               `json:"stop_sequences,omitempty"`
+	Stream        bool                   `json:"stream,omitempty"`
+	Tools         []AnthropicTool        `json:"tools,omitempty"`
+	ToolChoice    *AnthropicToolChoice   `json:"tool_choice,omitempty"`
+	Thinking      *AnthropicThinking     `json:"thinking,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// AnthropicMessage represents a message in an Anthropic conversation
+type AnthropicMessage struct {
+	Role    string           `json:"role"`
+	Content AnthropicContent `json:"content"`
+}
+
+// AnthropicContent is a list of content blocks, which may be sent as a plain string
+type AnthropicContent []AnthropicContentBlock
+
+// UnmarshalJSON accepts either a string or an array of content blocks
+func (c *AnthropicContent) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = AnthropicContent{{Type: "text", Text: text}}
+		return nil
+	}
+
+	var blocks []AnthropicContentBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return fmt.Errorf("content must be a string or an array of content blocks: %w", err)
+	}
+	*c = blocks
+	return nil
+}
+
+// AnthropicContentBlock represents a text, image, tool_use, tool_result or thinking block
+type AnthropicContentBlock struct {
+	Type string `json:"type"`
+
+	// Text is set on text blocks
+	Text string `json:"text,omitempty"`
+
+	// Source is set on image blocks
+	Source *AnthropicImageSource `json:"source,omitempty"`
+
+	// ID, Name and Input are set on tool_use blocks
+	ID    string      `json:"id,omitempty"`
+	Name  string      `json:"name,omitempty"`
+	Input interface{} `json:"input,omitempty"`
+
+	// ToolUseID, Content and IsError are set on tool_result blocks
+	ToolUseID string           `json:"tool_use_id,omitempty"`
+	Content   AnthropicContent `json:"content,omitempty"`
+	IsError   bool             `json:"is_error,omitempty"`
+
+	// Thinking and Signature are set on thinking blocks
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// AnthropicImageSource represents the source of an image block
+type AnthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// AnthropicTool represents a client tool definition
+type AnthropicTool struct {
+	Type        string                 `json:"type,omitempty"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema,omitempty"`
+}
+
+// AnthropicToolChoice controls how the model uses the provided tools
+type AnthropicToolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+// AnthropicThinking enables extended thinking with a token budget
+type AnthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens,omitempty"`
+}
+
+// AnthropicMessagesResponse represents an Anthropic Messages API response
+type AnthropicMessagesResponse struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []AnthropicContentBlock `json:"content"`
+	StopReason   *string                 `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        AnthropicUsage          `json:"usage"`
+}
+
+// AnthropicUsage represents token usage in Anthropic format
+type AnthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// AnthropicCountTokensResponse represents the response of the count_tokens endpoint
+type AnthropicCountTokensResponse struct {
+	InputTokens int `json:"input_tokens"`
+}
+
+// AnthropicError represents an error object in Anthropic format
+type AnthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// AnthropicErrorResponse represents an error response in Anthropic format
+type AnthropicErrorResponse struct {
+	Type  string         `json:"type"`
+	Error AnthropicError `json:"error"`
+}
@@ -0,0 +1,168 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ResponsesRequest represents an OpenAI Responses API request
+type ResponsesRequest struct {
+	Model              string                 `json:"model" binding:"required"`
+	Input              ResponsesInput         `json:"input"`
+	Instructions       string                 `json:"instructions,omitempty"`
+	Tools              []ResponsesTool        `json:"tools,omitempty"`
+	ToolChoice         interface{}            `json:"tool_choice,omitempty"`
+	ParallelToolCalls  *bool                  `json:"parallel_tool_calls,omitempty"`
+	Temperature        *float64               `json:"temperature,omitempty"`
+	TopP               *float64               `json:"top_p,omitempty"`
+	MaxOutputTokens    *int                   `json:"max_output_tokens,omitempty"`
+	Stream             bool                   `json:"stream,omitempty"`
+	Store              *bool                  `json:"store,omitempty"`
+	PreviousResponseID string                 `json:"previous_response_id,omitempty"`
+	Reasoning          *ResponsesReasoning    `json:"reasoning,omitempty"`
+	Text               *ResponsesText         `json:"text,omitempty"`
+	Metadata           map[string]interface{} `json:"metadata,omitempty"`
+
+	// Logprobs have no Gemini equivalent, they are requested with top_logprobs or
+	// the message.output_text.logprobs include, see UNSUPPORTED_PARAMS
+	TopLogprobs *int     `json:"top_logprobs,omitempty"`
+	Include     []string `json:"include,omitempty"`
+}
+
+// ResponsesInput is a list of input items, which may be sent as a plain string
+type ResponsesInput []ResponsesItem
+
+// UnmarshalJSON accepts either a string or an array of input items
+func (in *ResponsesInput) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*in = ResponsesInput{{Type: "message", Role: "user", Content: text}}
+		return nil
+	}
+
+	var items []ResponsesItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return fmt.Errorf("input must be a string or an array of input items: %w", err)
+	}
+	*in = items
+	return nil
+}
+
+// ResponsesItem represents an input or output item: a message, function call,
+// function call output or reasoning item
+type ResponsesItem struct {
+	Type   string `json:"type,omitempty"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status,omitempty"`
+
+	// Role and Content are set on messages, content is a string or a list of content parts
+	Role    string      `json:"role,omitempty"`
+	Content interface{} `json:"content,omitempty"`
+
+	// CallID, Name and Arguments are set on function calls, CallID and Output on their outputs
+	CallID    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Output    string `json:"output,omitempty"`
+
+	// Summary is set on reasoning items
+	Summary []ResponsesSummaryPart `json:"summary,omitempty"`
+}
+
+// ResponsesContentPart represents a content part of an output message
+type ResponsesContentPart struct {
+	Type        string        `json:"type"`
+	Text        string        `json:"text"`
+	Annotations []interface{} `json:"annotations"`
+}
+
+// ResponsesSummaryPart represents a reasoning summary part
+type ResponsesSummaryPart struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// ResponsesTool represents a function tool in the Responses API
+type ResponsesTool struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
+}
+
+// ResponsesReasoning configures reasoning for reasoning models
+type ResponsesReasoning struct {
+	Effort  string `json:"effort,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// ResponsesText configures the text output of a response
+type ResponsesText struct {
+	Format *ResponsesTextFormat `json:"format,omitempty"`
+}
+
+// ResponsesTextFormat represents the output format, the Responses API flattens the json_schema fields
+type ResponsesTextFormat struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
+}
+
+// ResponsesResponse represents an OpenAI Responses API response object
+type ResponsesResponse struct {
+	ID                 string                     `json:"id"`
+	Object             string                     `json:"object"`
+	CreatedAt          int64                      `json:"created_at"`
+	Status             string                     `json:"status"`
+	Model              string                     `json:"model"`
+	Output             []ResponsesItem            `json:"output"`
+	Instructions       *string                    `json:"instructions"`
+	PreviousResponseID *string                    `json:"previous_response_id"`
+	Error              *ResponsesError            `json:"error"`
+	IncompleteDetails  *ResponsesIncompleteDetail `json:"incomplete_details"`
+	Tools              []ResponsesTool            `json:"tools"`
+	ToolChoice         interface{}                `json:"tool_choice"`
+	ParallelToolCalls  bool                       `json:"parallel_tool_calls"`
+	Temperature        *float64                   `json:"temperature"`
+	TopP               *float64                   `json:"top_p"`
+	MaxOutputTokens    *int                       `json:"max_output_tokens"`
+	Metadata           map[string]interface{}     `json:"metadata"`
+	Usage              *ResponsesUsage            `json:"usage"`
+}
+
+// ResponsesError represents the error of a failed response
+type ResponsesError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ResponsesIncompleteDetail explains why a response is incomplete
+type ResponsesIncompleteDetail struct {
+	Reason string `json:"reason"`
+}
+
+// ResponsesUsage represents token usage in the Responses API
+type ResponsesUsage struct {
+	InputTokens         int                         `json:"input_tokens"`
+	InputTokensDetails  ResponsesInputTokensDetail  `json:"input_tokens_details"`
+	OutputTokens        int                         `json:"output_tokens"`
+	OutputTokensDetails ResponsesOutputTokensDetail `json:"output_tokens_details"`
+	TotalTokens         int                         `json:"total_tokens"`
+}
+
+// ResponsesInputTokensDetail breaks down the input tokens
+type ResponsesInputTokensDetail struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// ResponsesOutputTokensDetail breaks down the output tokens
+type ResponsesOutputTokensDetail struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
@@ -1,21 +1,48 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // Environment represents the application environment configuration
 type Environment struct {
-	GCPServiceAccount      string `json:"gcp_service_account"`
-	GeminiProjectID        string `json:"gemini_project_id"`
-	GoogleClientID         string `json:"google_client_id"`
-	GoogleClientSecret     string `json:"google_client_secret"`
-	OpenAIAPIKey           string `json:"openai_api_key"`
-	EnableFakeThinking     string `json:"enable_fake_thinking"`
-	EnableRealThinking     string `json:"enable_real_thinking"`
-	StreamThinkingAsContent string `json:"stream_thinking_as_content"`
-	Port                   string `json:"port"`
-	LogLevel               string `json:"log_level"`
-	TokenCacheExpiry       int    `json:"token_cache_expiry"`
-	RequestTimeout         int    `json:"request_timeout"`
+	GCPServiceAccount        string `json:"gcp_service_account"`
+	GeminiProjectID          string `json:"gemini_project_id"`
+	CodeAssistEndpoint       string `json:"code_assist_endpoint"`
+	GoogleClientID           string `json:"google_client_id"`
+	GoogleClientSecret       string `json:"google_client_secret"`
+	OpenAIAPIKey             string `json:"openai_api_key"`
+	EnableFakeThinking       string `json:"enable_fake_thinking"`
+	EnableRealThinking       string `json:"enable_real_thinking"`
+	StreamThinkingAsContent  string `json:"stream_thinking_as_content"`
+	Port                     string `json:"port"`
+	LogLevel                 string `json:"log_level"`
+	TokenCacheExpiry         int    `json:"token_cache_expiry"`
+	RequestTimeout           int    `json:"request_timeout"`
+	UpstreamConnectTimeout   int    `json:"upstream_connect_timeout"`
+	UpstreamFirstByteTimeout int    `json:"upstream_first_byte_timeout"`
+	StreamIdleTimeout        int    `json:"stream_idle_timeout"`
+	StreamMaxDuration        int    `json:"stream_max_duration"`
+	SchemaValidationRetries  int    `json:"schema_validation_retries"`
+	SystemPromptMode         string `json:"system_prompt_mode"`
+	SafetySettings           string `json:"safety_settings"`
+	RetryMaxRetries          int    `json:"retry_max_retries"`
+	RetryInitialDelayMs      int    `json:"retry_initial_delay_ms"`
+	RetryMaxDelayMs          int    `json:"retry_max_delay_ms"`
+	RetryMaxElapsedMs        int    `json:"retry_max_elapsed_ms"`
+	AccountStrategy          string `json:"account_strategy"`
+	AccountCooldown          int    `json:"account_cooldown"`
+	ResponseStoreSize        int    `json:"response_store_size"`
+	ResponseStoreTTL         int    `json:"response_store_ttl"`
+	UnsupportedParams        string `json:"unsupported_params"`
+	ReasoningFormat          string `json:"reasoning_format"`
+	HistoryThinking          string `json:"history_thinking"`
+	SSEHeartbeatInterval     int    `json:"sse_heartbeat_interval"`
+	CodeExecutionFormat      string `json:"code_execution_format"`
+	WebSocketAllowedOrigins  string `json:"websocket_allowed_origins"`
+	WebSocketMaxConcurrent   int    `json:"websocket_max_concurrent"`
 }
 
 // OAuth2Credentials represents OAuth2 credentials from Gemini CLI
@@ -26,6 +53,28 @@ type OAuth2Credentials struct {
 	TokenType    string `json:"token_type"`
 	IDToken      string `json:"id_token"`
 	ExpiryDate   int64  `json:"expiry_date"`
+
+	// Label and ProjectID are optional and only used by the account pool
+	Label     string `json:"label,omitempty"`
+	ProjectID string `json:"project_id,omitempty"`
+}
+
+// AccountStatus represents the health of one account in the credential pool
+type AccountStatus struct {
+	Label                    string               `json:"label"`
+	ProjectID                string               `json:"project_id,omitempty"`
+	Authenticated            bool                 `json:"authenticated"`
+	State                    string               `json:"state"`
+	CooldownUntil            *time.Time           `json:"cooldown_until,omitempty"`
+	CooldownRemainingSeconds int                  `json:"cooldown_remaining_seconds,omitempty"`
+	CooldownModels           map[string]time.Time `json:"cooldown_models,omitempty"`
+	Requests                 int64                `json:"requests"`
+	RateLimits               int64                `json:"rate_limits"`
+	Failures                 int64                `json:"failures"`
+	LastUsed                 *time.Time           `json:"last_used,omitempty"`
+	LastRateLimited          *time.Time           `json:"last_rate_limited,omitempty"`
+	LastError                string               `json:"last_error,omitempty"`
+	TokenCache               *TokenCacheInfo      `json:"token_cache"`
 }
 
 // ModelInfo represents information about a Gemini model
@@ -38,24 +87,117 @@ type ModelInfo struct {
 	OutputPrice         float64 `json:"output_price"`
 	Description         string  `json:"description"`
 	Thinking            bool    `json:"thinking"`
+
+	// ThinkingBudgetMin and ThinkingBudgetMax bound the thinking budget of thinking models,
+	// ThinkingCanDisable reports whether a budget of 0 turns thinking off
+	ThinkingBudgetMin  int  `json:"thinking_budget_min,omitempty"`
+	ThinkingBudgetMax  int  `json:"thinking_budget_max,omitempty"`
+	ThinkingCanDisable bool `json:"thinking_can_disable,omitempty"`
+
+	// ThinkingBudgetLow and ThinkingBudgetMedium are the budgets of the low and medium reasoning efforts
+	ThinkingBudgetLow    int `json:"thinking_budget_low,omitempty"`
+	ThinkingBudgetMedium int `json:"thinking_budget_medium,omitempty"`
+
+	// Fallbacks lists the models tried in order when this model is exhausted or overloaded
+	Fallbacks []string `json:"fallbacks,omitempty"`
 }
 
 // ChatCompletionRequest represents an OpenAI chat completion request
 type ChatCompletionRequest struct {
-	Model          string        `json:"model"`
-	Messages       []ChatMessage `json:"messages"`
-	Stream         *bool         `json:"stream,omitempty"`
-	ThinkingBudget *int          `json:"thinking_budget,omitempty"`
-	Temperature    *float64      `json:"temperature,omitempty"`
-	MaxTokens      *int          `json:"max_tokens,omitempty"`
-	TopP           *float64      `json:"top_p,omitempty"`
-	Stop           []string      `json:"stop,omitempty"`
+	Model             string          `json:"model"`
+	Messages          []ChatMessage   `json:"messages"`
+	Stream            *bool           `json:"stream,omitempty"`
+	ThinkingBudget    *int            `json:"thinking_budget,omitempty"`
+	Temperature       *float64        `json:"temperature,omitempty"`
+	MaxTokens         *int            `json:"max_tokens,omitempty"`
+	TopP              *float64        `json:"top_p,omitempty"`
+	Stop              StopSequences   `json:"stop,omitempty"`
+	Tools             []Tool          `json:"tools,omitempty"`
+	ToolChoice        interface{}     `json:"tool_choice,omitempty"`
+	ParallelToolCalls *bool           `json:"parallel_tool_calls,omitempty"`
+	ResponseFormat    *ResponseFormat `json:"response_format,omitempty"`
+
+	ReasoningEffort     string   `json:"reasoning_effort,omitempty"`
+	IncludeReasoning    *bool    `json:"include_reasoning,omitempty"`
+	ReasoningFormat     string   `json:"reasoning_format,omitempty"`
+	CodeExecutionFormat string   `json:"code_execution_format,omitempty"`
+	MaxCompletionTokens *int     `json:"max_completion_tokens,omitempty"`
+	TopK                *int     `json:"top_k,omitempty"`
+	Seed                *int     `json:"seed,omitempty"`
+	PresencePenalty     *float64 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float64 `json:"frequency_penalty,omitempty"`
+	N                   *int     `json:"n,omitempty"`
+
+	// StreamOptions controls optional parts of a streamed response
+	StreamOptions *ChatStreamOptions `json:"stream_options,omitempty"`
+
+	// Logprobs, TopLogprobs and LogitBias have no Gemini equivalent, see UNSUPPORTED_PARAMS
+	Logprobs    *bool              `json:"logprobs,omitempty"`
+	TopLogprobs *int               `json:"top_logprobs,omitempty"`
+	LogitBias   map[string]float64 `json:"logit_bias,omitempty"`
+
+	// SafetySettings is a non-OpenAI extension that overrides the deployment's Gemini safety settings
+	SafetySettings []GeminiSafetySetting `json:"safety_settings,omitempty"`
+}
+
+// ChatStreamOptions represents the stream_options of a chat completion request
+type ChatStreamOptions struct {
+	IncludeUsage bool `json:"include_usage,omitempty"`
+}
+
+// ResponseFormat represents the requested output format of a chat completion
+type ResponseFormat struct {
+	Type       string                    `json:"type"`
+	JSONSchema *ResponseFormatJSONSchema `json:"json_schema,omitempty"`
+}
+
+// ResponseFormatJSONSchema represents the JSON schema of a json_schema response format
+type ResponseFormatJSONSchema struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
 }
 
 // ChatMessage represents a message in a chat conversation
 type ChatMessage struct {
-	Role    string      `json:"role"`
-	Content interface{} `json:"content"`
+	Role       string      `json:"role"`
+	Content    interface{} `json:"content"`
+	Name       string      `json:"name,omitempty"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+
+	// Reasoning and ReasoningContent carry the reasoning clients send back in assistant turns
+	Reasoning        string `json:"reasoning,omitempty"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// Tool represents a tool definition in an OpenAI request
+type Tool struct {
+	Type     string        `json:"type"`
+	Function *ToolFunction `json:"function,omitempty"`
+}
+
+// ToolFunction represents a function that the model may call
+type ToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Strict      *bool                  `json:"strict,omitempty"`
+}
+
+// ToolCall represents a tool call made by the assistant
+type ToolCall struct {
+	Index    *int             `json:"index,omitempty"`
+	ID       string           `json:"id,omitempty"`
+	Type     string           `json:"type,omitempty"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction represents the function name and JSON encoded arguments of a tool call
+type ToolCallFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
 }
 
 // MessageContent represents structured content within a message
@@ -73,12 +215,12 @@ type ImageURL struct {
 
 // ChatCompletionResponse represents an OpenAI chat completion response
 type ChatCompletionResponse struct {
-	ID      string                   `json:"id"`
-	Object  string                   `json:"object"`
-	Created int64                    `json:"created"`
-	Model   string                   `json:"model"`
-	Choices []ChatCompletionChoice   `json:"choices"`
-	Usage   *ChatCompletionUsage     `json:"usage,omitempty"`
+	ID      string                 `json:"id"`
+	Object  string                 `json:"object"`
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model"`
+	Choices []ChatCompletionChoice `json:"choices"`
+	Usage   *ChatCompletionUsage   `json:"usage,omitempty"`
 }
 
 // ChatCompletionChoice represents a choice in a chat completion response
@@ -87,32 +229,95 @@ type ChatCompletionChoice struct {
 	Message      *ChatCompletionMessage `json:"message,omitempty"`
 	Delta        *ChatCompletionDelta   `json:"delta,omitempty"`
 	FinishReason *string                `json:"finish_reason"`
+
+	// SafetyRatings is a non-OpenAI extension carrying Gemini's safety ratings for the choice
+	SafetyRatings []GeminiSafetyRating `json:"safety_ratings,omitempty"`
 }
 
 // ChatCompletionMessage represents a message in a chat completion response
 type ChatCompletionMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role             string          `json:"role"`
+	Content          string          `json:"content"`
+	Reasoning        string          `json:"reasoning,omitempty"`
+	ReasoningContent string          `json:"reasoning_content,omitempty"`
+	ToolCalls        []ToolCall      `json:"tool_calls,omitempty"`
+	Annotations      []Annotation    `json:"annotations,omitempty"`
+	CodeExecution    []CodeExecution `json:"code_execution,omitempty"`
 }
 
 // ChatCompletionDelta represents a delta in a streaming chat completion response
 type ChatCompletionDelta struct {
-	Role      string `json:"role,omitempty"`
-	Content   string `json:"content,omitempty"`
-	Reasoning string `json:"reasoning,omitempty"`
+	Role             string          `json:"role,omitempty"`
+	Content          string          `json:"content,omitempty"`
+	Reasoning        string          `json:"reasoning,omitempty"`
+	ReasoningContent string          `json:"reasoning_content,omitempty"`
+	ToolCalls        []ToolCall      `json:"tool_calls,omitempty"`
+	Annotations      []Annotation    `json:"annotations,omitempty"`
+	CodeExecution    []CodeExecution `json:"code_execution,omitempty"`
+}
+
+// Annotation represents an annotation of message content, such as a URL citation
+type Annotation struct {
+	Type        string       `json:"type"`
+	URLCitation *URLCitation `json:"url_citation,omitempty"`
+}
+
+// URLCitation cites the web page a span of the message content is based on. The indices count
+// characters of the content, the end index is exclusive.
+type URLCitation struct {
+	StartIndex int    `json:"start_index"`
+	EndIndex   int    `json:"end_index"`
+	URL        string `json:"url"`
+	Title      string `json:"title"`
+}
+
+// CodeExecution is an extension field carrying code run by Gemini's code execution tool, or the
+// result of running it, when code execution is returned in the structured format
+type CodeExecution struct {
+	Type     string `json:"type"`
+	Language string `json:"language,omitempty"`
+	Code     string `json:"code,omitempty"`
+	Outcome  string `json:"outcome,omitempty"`
+	Output   string `json:"output,omitempty"`
 }
 
 // ChatCompletionUsage represents usage information in a chat completion response
 type ChatCompletionUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
 }
 
-// UsageData represents token usage information
+// PromptTokensDetails breaks down the prompt tokens
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// CompletionTokensDetails breaks down the completion tokens
+type CompletionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+// UsageData represents token usage information, output tokens include the reasoning tokens
+// and input tokens include the cached and tool use prompt tokens
 type UsageData struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens     int `json:"input_tokens"`
+	OutputTokens    int `json:"output_tokens"`
+	ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+	CachedTokens    int `json:"cached_tokens,omitempty"`
+}
+
+// ChatCompletionUsage returns the usage in OpenAI format
+func (u UsageData) ChatCompletionUsage() *ChatCompletionUsage {
+	return &ChatCompletionUsage{
+		PromptTokens:            u.InputTokens,
+		CompletionTokens:        u.OutputTokens,
+		TotalTokens:             u.InputTokens + u.OutputTokens,
+		PromptTokensDetails:     &PromptTokensDetails{CachedTokens: u.CachedTokens},
+		CompletionTokensDetails: &CompletionTokensDetails{ReasoningTokens: u.ReasoningTokens},
+	}
 }
 
 // ReasoningData represents reasoning information
@@ -120,21 +325,38 @@ type ReasoningData struct {
 	Reasoning string `json:"reasoning"`
 }
 
+// FinishData represents why a candidate stopped, along with its final safety ratings
+type FinishData struct {
+	FinishReason  string               `json:"finish_reason"`
+	SafetyRatings []GeminiSafetyRating `json:"safety_ratings,omitempty"`
+}
+
 // StreamChunk represents a chunk of streaming data
 type StreamChunk struct {
 	Type StreamChunkType `json:"type"`
 	Data interface{}     `json:"data"`
+
+	// Index is the index of the response candidate the chunk belongs to
+	Index int `json:"index,omitempty"`
 }
 
 // StreamChunkType represents the type of stream chunk
 type StreamChunkType string
 
 const (
-	StreamChunkTypeText          StreamChunkType = "text"
-	StreamChunkTypeUsage         StreamChunkType = "usage"
-	StreamChunkTypeReasoning     StreamChunkType = "reasoning"
+	StreamChunkTypeText            StreamChunkType = "text"
+	StreamChunkTypeUsage           StreamChunkType = "usage"
+	StreamChunkTypeReasoning       StreamChunkType = "reasoning"
 	StreamChunkTypeThinkingContent StreamChunkType = "thinking_content"
-	StreamChunkTypeRealThinking  StreamChunkType = "real_thinking"
+	StreamChunkTypeRealThinking    StreamChunkType = "real_thinking"
+	StreamChunkTypeToolCall        StreamChunkType = "tool_call"
+	StreamChunkTypeFinishReason    StreamChunkType = "finish_reason"
+	StreamChunkTypeError           StreamChunkType = "error"
+	StreamChunkTypeModel           StreamChunkType = "model"
+	StreamChunkTypeRaw             StreamChunkType = "raw"
+	StreamChunkTypeHeartbeat       StreamChunkType = "heartbeat"
+	StreamChunkTypeAnnotations     StreamChunkType = "annotations"
+	StreamChunkTypeCodeExecution   StreamChunkType = "code_execution"
 )
 
 // TokenRefreshResponse represents a token refresh response
@@ -165,16 +387,67 @@ type TokenCacheInfo struct {
 // GeminiResponse represents a response from the Gemini API
 type GeminiResponse struct {
 	Response *struct {
-		Candidates    []GeminiCandidate    `json:"candidates"`
-		UsageMetadata *GeminiUsageMetadata `json:"usageMetadata"`
+		Candidates     []GeminiCandidate     `json:"candidates"`
+		UsageMetadata  *GeminiUsageMetadata  `json:"usageMetadata"`
+		PromptFeedback *GeminiPromptFeedback `json:"promptFeedback,omitempty"`
 	} `json:"response"`
 }
 
 // GeminiCandidate represents a candidate in a Gemini response
 type GeminiCandidate struct {
+	Index   int `json:"index"`
 	Content *struct {
 		Parts []GeminiPart `json:"parts"`
 	} `json:"content"`
+	FinishReason      string                   `json:"finishReason,omitempty"`
+	SafetyRatings     []GeminiSafetyRating     `json:"safetyRatings,omitempty"`
+	GroundingMetadata *GeminiGroundingMetadata `json:"groundingMetadata,omitempty"`
+}
+
+// GeminiGroundingMetadata describes the sources Google Search or URL context grounded a candidate on
+type GeminiGroundingMetadata struct {
+	WebSearchQueries  []string                 `json:"webSearchQueries,omitempty"`
+	GroundingChunks   []GeminiGroundingChunk   `json:"groundingChunks,omitempty"`
+	GroundingSupports []GeminiGroundingSupport `json:"groundingSupports,omitempty"`
+}
+
+// GeminiGroundingChunk represents a source of grounding metadata
+type GeminiGroundingChunk struct {
+	Web *struct {
+		URI   string `json:"uri"`
+		Title string `json:"title"`
+	} `json:"web,omitempty"`
+}
+
+// GeminiGroundingSupport links a segment of the candidate text to the grounding chunks supporting it.
+// Segment indices are byte offsets into the candidate text, the end index is exclusive.
+type GeminiGroundingSupport struct {
+	Segment struct {
+		StartIndex int    `json:"startIndex,omitempty"`
+		EndIndex   int    `json:"endIndex,omitempty"`
+		Text       string `json:"text,omitempty"`
+	} `json:"segment"`
+	GroundingChunkIndices []int `json:"groundingChunkIndices,omitempty"`
+}
+
+// GeminiSafetyRating represents the safety rating of a prompt or candidate for one harm category
+type GeminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
+// GeminiSafetySetting represents the block threshold for one harm category
+type GeminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+// GeminiPromptFeedback represents the feedback Gemini returns about the prompt
+type GeminiPromptFeedback struct {
+	BlockReason        string               `json:"blockReason,omitempty"`
+	BlockReasonMessage string               `json:"blockReasonMessage,omitempty"`
+	SafetyRatings      []GeminiSafetyRating `json:"safetyRatings,omitempty"`
 }
 
 // GeminiPart represents a part in a Gemini candidate
@@ -189,12 +462,43 @@ type GeminiPart struct {
 		MimeType string `json:"mimeType"`
 		FileURI  string `json:"fileUri"`
 	} `json:"fileData,omitempty"`
+	FunctionCall        *GeminiFunctionCall        `json:"functionCall,omitempty"`
+	FunctionResponse    *GeminiFunctionResponse    `json:"functionResponse,omitempty"`
+	ExecutableCode      *GeminiExecutableCode      `json:"executableCode,omitempty"`
+	CodeExecutionResult *GeminiCodeExecutionResult `json:"codeExecutionResult,omitempty"`
+}
+
+// GeminiExecutableCode represents code generated and run by Gemini's code execution tool
+type GeminiExecutableCode struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+// GeminiCodeExecutionResult represents the result of running executable code
+type GeminiCodeExecutionResult struct {
+	Outcome string `json:"outcome"`
+	Output  string `json:"output,omitempty"`
+}
+
+// GeminiFunctionCall represents a function call requested by Gemini
+type GeminiFunctionCall struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// GeminiFunctionResponse represents the result of a function call sent back to Gemini
+type GeminiFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
 }
 
 // GeminiUsageMetadata represents usage metadata in a Gemini response
 type GeminiUsageMetadata struct {
-	PromptTokenCount     int `json:"promptTokenCount"`
-	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount,omitempty"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount,omitempty"`
+	ToolUsePromptTokenCount int `json:"toolUsePromptTokenCount,omitempty"`
 }
 
 // GeminiFormattedMessage represents a message formatted for Gemini API
@@ -215,6 +519,19 @@ type ErrorResponse struct {
 	Code    int    `json:"code,omitempty"`
 }
 
+// OpenAIError represents an error object in OpenAI format
+type OpenAIError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    *string `json:"code"`
+}
+
+// OpenAIErrorResponse represents an error response in OpenAI format
+type OpenAIErrorResponse struct {
+	Error OpenAIError `json:"error"`
+}
+
 // ModelListResponse represents a model list response
 type ModelListResponse struct {
 	Object string      `json:"object"`
@@ -265,6 +582,7 @@ type AuthInfo struct {
 // EndpointInfo represents endpoint information
 type EndpointInfo struct {
 	ChatCompletions string      `json:"chat_completions"`
+	Messages        string      `json:"messages"`
 	Models          string      `json:"models"`
 	Debug           DebugRoutes `json:"debug"`
 }
@@ -274,4 +592,74 @@ type DebugRoutes struct {
 	Cache     string `json:"cache"`
 	TokenTest string `json:"token_test"`
 	FullTest  string `json:"full_test"`
-}
\ No newline at end of file
+}
+
+// GoogleError represents an error object in Google API format
+type GoogleError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// GoogleErrorResponse represents an error response in Google API format
+type GoogleErrorResponse struct {
+	Error GoogleError `json:"error"`
+}
+
+// StopSequences is a list of stop sequences, which may be sent as a single string
+type StopSequences []string
+
+// UnmarshalJSON accepts either a string or an array of strings
+func (s *StopSequences) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = StopSequences{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("stop must be a string or an array of strings: %w", err)
+	}
+	*s = list
+	return nil
+}
+
+// CompletionRequest represents a legacy OpenAI text completion request
+type CompletionRequest struct {
+	Model       string        `json:"model" binding:"required"`
+	Prompt      interface{}   `json:"prompt"`
+	Suffix      string        `json:"suffix,omitempty"`
+	MaxTokens   *int          `json:"max_tokens,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
+	Stop        StopSequences `json:"stop,omitempty"`
+	N           *int          `json:"n,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
+
+	// Logprobs and LogitBias have no Gemini equivalent, see UNSUPPORTED_PARAMS
+	Logprobs  *int               `json:"logprobs,omitempty"`
+	LogitBias map[string]float64 `json:"logit_bias,omitempty"`
+}
+
+// CompletionResponse represents a legacy text completion response or stream chunk
+type CompletionResponse struct {
+	ID      string               `json:"id"`
+	Object  string               `json:"object"`
+	Created int64                `json:"created"`
+	Model   string               `json:"model"`
+	Choices []CompletionChoice   `json:"choices"`
+	Usage   *ChatCompletionUsage `json:"usage,omitempty"`
+}
+
+// CompletionChoice represents a choice in a text completion response
+type CompletionChoice struct {
+	Text         string      `json:"text"`
+	Index        int         `json:"index"`
+	Logprobs     interface{} `json:"logprobs"`
+	FinishReason *string     `json:"finish_reason"`
+}
@@ -0,0 +1,18 @@
+package types
+
+// ChatWebSocketMessage holds the fields shared by the messages clients send on /v1/chat/ws.
+// A message without a type is a chat completion request tagged with a client-chosen id,
+// a cancel message cancels the running completion with the id.
+type ChatWebSocketMessage struct {
+	Type string `json:"type,omitempty"`
+	ID   string `json:"id"`
+}
+
+// ChatWebSocketResponse is a message the server sends on /v1/chat/ws, tagged with the id of the
+// completion it belongs to. Errors about a message without a usable id have an empty id.
+type ChatWebSocketResponse struct {
+	Type  string                  `json:"type"`
+	ID    string                  `json:"id"`
+	Chunk *ChatCompletionResponse `json:"chunk,omitempty"`
+	Error *OpenAIError            `json:"error,omitempty"`
+}
@@ -43,7 +43,8 @@ func main() {
 		engine = routes.SetupRoutes(cfg)
 	}
 
-	// Create HTTP server
+	// Create HTTP server, its timeouts are the hard limit of non-streaming requests.
+	// Streaming responses replace them with STREAM_MAX_DURATION.
 	server := &http.Server{
 		Addr:           cfg.GetAddress(),
 		Handler:        engine,